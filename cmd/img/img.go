@@ -6,6 +6,7 @@ import (
 
 	"handytools/internal/batchrename"
 	"handytools/internal/collage"
+	"handytools/internal/contactsheet"
 	"handytools/internal/distort"
 	"handytools/internal/frame"
 	"handytools/internal/gallery"
@@ -28,6 +29,7 @@ var rootCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(batchrename.Cmd)
 	rootCmd.AddCommand(collage.Cmd)
+	rootCmd.AddCommand(contactsheet.Cmd)
 	rootCmd.AddCommand(distort.Cmd)
 	rootCmd.AddCommand(frame.Cmd)
 	rootCmd.AddCommand(grab.Cmd)
@@ -11,6 +11,7 @@ require (
 	github.com/ncruces/zenity v0.10.14
 	github.com/sirupsen/logrus v1.9.3
 	github.com/spf13/cobra v1.9.1
+	golang.org/x/image v0.20.0
 )
 
 require (
@@ -27,6 +28,6 @@ require (
 	github.com/randall77/makefat v0.0.0-20210315173500-7ddd0e42c844 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 	github.com/stretchr/testify v1.8.4 // indirect
-	golang.org/x/image v0.20.0 // indirect
 	golang.org/x/sys v0.32.0 // indirect
+	golang.org/x/text v0.18.0 // indirect
 )
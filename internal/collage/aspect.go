@@ -0,0 +1,76 @@
+package collage
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultCanvasWidth is the canvas width when neither --width nor --size is
+// given.
+const defaultCanvasWidth = 2048
+
+// aspectPresets map names to width/height ratios.
+var aspectPresets = map[string]float64{
+	"square":    1,
+	"portrait":  4.0 / 5,
+	"story":     9.0 / 16,
+	"landscape": 16.0 / 9,
+	"print":     3.0 / 2,
+	"a4":        1 / math.Sqrt2,
+}
+
+// parseAspect reads "free", a preset name or a ratio written W:H or WxH.
+// Free returns 0.
+func parseAspect(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "free" || s == "" {
+		return 0, nil
+	}
+	if a, ok := aspectPresets[s]; ok {
+		return a, nil
+	}
+	w, h, err := parsePair(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid aspect %q (use free, W:H or square, portrait, story, landscape, print, a4)", s)
+	}
+	return w / h, nil
+}
+
+// parseSize reads an exact canvas size written WxH in pixels.
+func parseSize(s string) (image.Point, error) {
+	w, h, err := parsePair(strings.ToLower(strings.TrimSpace(s)))
+	if err != nil || w != math.Trunc(w) || h != math.Trunc(h) {
+		return image.Point{}, fmt.Errorf("invalid size %q (use WxH in pixels, e.g. 1080x1350)", s)
+	}
+	return image.Pt(int(w), int(h)), nil
+}
+
+func parsePair(s string) (float64, float64, error) {
+	a, b, ok := strings.Cut(s, ":")
+	if !ok {
+		a, b, ok = strings.Cut(s, "x")
+	}
+	if !ok {
+		return 0, 0, fmt.Errorf("missing separator")
+	}
+	w, err1 := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	h, err2 := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("not two positive numbers")
+	}
+	return w, h, nil
+}
+
+// medianAspect is the median width/height ratio of sizes.
+func medianAspect(sizes []image.Point) float64 {
+	aspects := make([]float64, 0, len(sizes))
+	for _, s := range sizes {
+		aspects = append(aspects, float64(s.X)/float64(s.Y))
+	}
+	sort.Float64s(aspects)
+	return aspects[len(aspects)/2]
+}
@@ -2,6 +2,7 @@ package collage
 
 import (
 	"handytools/pkg/common"
+	"handytools/pkg/smartcrop"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
@@ -12,7 +13,20 @@ type Config struct {
 	OutputFile  string
 	Rows        int
 	Columns     int
-	AspectRatio string // "free" or "4x5"
+	Template    string // Preset name or drawing such as "AAB/AAC"; overrides Rows and Columns
+	Width       int    // Canvas width; see canvasSize for the defaults
+	Height      int    // Canvas height; see canvasSize for the defaults
+	Size        string // Exact canvas size as WxH, overriding Width, Height and AspectRatio
+	Gutter      int    // Space between cells
+	GutterColor string // Colour of gutters, border and empty cells
+	Border      int    // Space around the edge of the canvas
+	Radius      int    // Corner radius of every cell
+	Overflow    string // "error" or "pages" when there are more images than cells
+	AspectRatio string // "free", a preset or W:H
+	Crop        string // "center", "entropy", "attention" or "face-rect"
+	FocusFile   string // Sidecar with manual focus points or regions
+	PDF         string // Also write the collage as a one-page PDF
+	DPI         int    // Print resolution that sets the PDF page size
 }
 
 var (
@@ -23,27 +37,87 @@ var (
 var Cmd = &cobra.Command{
 	Use:   "collage",
 	Short: "Create an image collage",
-	Long:  "Combines multiple images into a collage with the specified rows and columns.",
+	Long: `Combines multiple images into a collage with the specified rows and columns,
+or a template of cells spanning several slots, such as one large hero image
+beside four small ones.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
 			logger.Error("No images provided. Use wildcard or file list.")
 			return
 		}
 		config.InputFiles = expandWildcards(args)
-		if config.AspectRatio != "free" && config.AspectRatio != "4x5" {
-			logger.Error("Invalid aspect ratio. Use 'free' or '4x5'")
+		if config.Overflow != overflowError && config.Overflow != overflowPages {
+			logger.Error("Invalid overflow. Use 'error' or 'pages'")
 			return
 		}
+		if _, err := parseAspect(config.AspectRatio); err != nil {
+			logger.WithError(err).Error("Invalid aspect ratio")
+			return
+		}
+		if config.Size != "" {
+			s, err := parseSize(config.Size)
+			if err != nil {
+				logger.WithError(err).Error("Invalid size")
+				return
+			}
+			config.Width, config.Height = s.X, s.Y
+			if cmd.Flags().Changed("aspect") {
+				logger.Warn("--size sets the exact canvas, ignoring --aspect")
+			}
+		}
+		if config.Crop != cropFaceRect {
+			if _, err := smartcrop.ParseStrategy(config.Crop); err != nil {
+				logger.WithError(err).Error("Invalid crop")
+				return
+			}
+		} else if config.FocusFile == "" {
+			logger.Error("--crop face-rect needs face regions from --focus")
+			return
+		}
+		if config.PDF != "" && !cmd.Flags().Changed("output") {
+			config.OutputFile = ""
+		}
 		logger.Infof("Running collage with config: %+v", config)
-		createCollage(config)
+		if err := createCollage(config); err != nil {
+			logger.WithError(err).Error("Failed to create collage")
+		}
 	},
 }
 
 func init() {
 	Cmd.Flags().IntVarP(&config.Rows, "rows", "r", 1, "Number of rows")
 	Cmd.Flags().IntVarP(&config.Columns, "columns", "c", 1, "Number of columns")
-	Cmd.Flags().StringVarP(&config.OutputFile, "output", "o", "collage.jpg", "Output file")
-	Cmd.Flags().StringVarP(&config.AspectRatio, "aspect", "a", "free", "Output aspect ratio: 'free' or '4x5'")
+	Cmd.Flags().StringVarP(&config.OutputFile, "output", "o", "collage.jpg", "Output file, .jpg or .png; extra pages are numbered (collage_01.jpg, ...)")
+	Cmd.Flags().StringVarP(&config.Template, "template", "t", "", `Cell layout instead of rows x columns: a preset or a drawing with rows
+separated by "/", one letter per cell and "." for empty slots; cells are
+filled in letter order. Presets:
+  hero     = AABC/AADE   (one large, four small)
+  hero-top = AAA/AAA/BCD (one large above three small)
+  feature  = AAB/AAC/DEF (one large, five small)`)
+	Cmd.Flags().IntVar(&config.Width, "width", 0, "Canvas width in pixels (default 2048, or from --height and --aspect)")
+	Cmd.Flags().IntVar(&config.Height, "height", 0, "Canvas height in pixels (default from the width and --aspect)")
+	Cmd.Flags().StringVar(&config.Size, "size", "", "Exact canvas size in pixels as WxH, e.g. 1080x1350")
+	Cmd.Flags().IntVar(&config.Gutter, "gutter", 0, "Space between cells in pixels")
+	Cmd.Flags().StringVar(&config.GutterColor, "gutter-color", "white", "Colour of gutters, border and empty cells: white, black, cream, ivory, transparent (PNG), #RRGGBB or #RRGGBBAA")
+	Cmd.Flags().IntVar(&config.Border, "border", 0, "Border around the collage in pixels")
+	Cmd.Flags().IntVar(&config.Radius, "radius", 0, "Corner radius of each cell in pixels")
+	Cmd.Flags().StringVar(&config.Overflow, "overflow", overflowError, "When there are more images than cells: 'error' or 'pages' for numbered extra collages")
+	Cmd.Flags().StringVarP(&config.AspectRatio, "aspect", "a", "free", `Canvas aspect ratio as W:H (e.g. 4:5, 2:1) or a preset:
+  free      = cells take the median aspect ratio of the images
+  square    = 1:1
+  portrait  = 4:5
+  story     = 9:16
+  landscape = 16:9
+  print     = 3:2
+  a4        = 1:1.414`)
+	Cmd.Flags().StringVar(&config.Crop, "crop", "center", `How images are cropped to their cells:
+  center    = keep the middle
+  entropy   = keep the region with the most detail
+  attention = keep the most salient region: detail, skin tones, colour
+  face-rect = centre on the region given in --focus, attention when missing`)
+	Cmd.Flags().StringVar(&config.FocusFile, "focus", "", "Sidecar file of path<TAB>x,y or path<TAB>x,y,w,h focus points (fractions or pixels); overrides --crop for the listed images")
+	Cmd.Flags().StringVar(&config.PDF, "pdf", "", "Write the collage as a PDF; the image file is only written as well when --output is given")
+	Cmd.Flags().IntVar(&config.DPI, "dpi", 150, "Print resolution that sets the PDF page size")
 }
 
 func expandWildcards(patterns []string) []string {
@@ -0,0 +1,124 @@
+package collage
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+)
+
+// cell is a rectangle of grid slots occupied by one image.
+type cell struct {
+	col, row   int
+	cols, rows int
+}
+
+// template is a grid of cols x rows slots and the cells laid over it, in the
+// order images are assigned to them.
+type template struct {
+	cols, rows int
+	cells      []cell
+}
+
+// templatePresets name common templates; see parseTemplate for the syntax.
+var templatePresets = map[string]string{
+	"hero":     "AABC/AADE",   // One large image and four small ones to its right
+	"hero-top": "AAA/AAA/BCD", // One large image above three small ones
+	"feature":  "AAB/AAC/DEF", // One large image and five small ones around it
+}
+
+// gridTemplate has one cell per slot, filled row by row.
+func gridTemplate(cols, rows int) template {
+	t := template{cols: cols, rows: rows}
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			t.cells = append(t.cells, cell{col: c, row: r, cols: 1, rows: 1})
+		}
+	}
+	return t
+}
+
+// parseTemplate reads a preset name or a drawing of the grid with rows
+// separated by "/", such as "AAB/AAC". Every letter (or other symbol) marks
+// the slots of one cell and must form a rectangle; "." leaves a slot empty.
+// Cells receive images in the sort order of their symbols.
+func parseTemplate(spec string) (template, error) {
+	if preset, ok := templatePresets[strings.ToLower(spec)]; ok {
+		spec = preset
+	}
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(spec), " ", ""), "/")
+	t := template{rows: len(lines), cols: len([]rune(lines[0]))}
+	if t.cols == 0 {
+		return template{}, fmt.Errorf("empty template")
+	}
+
+	bounds := map[rune]image.Rectangle{}
+	slots := map[rune]int{}
+	for r, line := range lines {
+		runes := []rune(line)
+		if len(runes) != t.cols {
+			return template{}, fmt.Errorf("template row %q has %d slots, want %d", line, len(runes), t.cols)
+		}
+		for c, sym := range runes {
+			if sym == '.' {
+				continue
+			}
+			slot := image.Rect(c, r, c+1, r+1)
+			if b, ok := bounds[sym]; ok {
+				slot = b.Union(slot)
+			}
+			bounds[sym] = slot
+			slots[sym]++
+		}
+	}
+
+	var symbols []rune
+	for sym, b := range bounds {
+		if b.Dx()*b.Dy() != slots[sym] {
+			return template{}, fmt.Errorf("cell %q in template %q is not a rectangle", sym, spec)
+		}
+		symbols = append(symbols, sym)
+	}
+	if len(symbols) == 0 {
+		return template{}, fmt.Errorf("template %q has no cells", spec)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i] < symbols[j] })
+	for _, sym := range symbols {
+		b := bounds[sym]
+		t.cells = append(t.cells, cell{col: b.Min.X, row: b.Min.Y, cols: b.Dx(), rows: b.Dy()})
+	}
+	return t, nil
+}
+
+// rects places the cells on a canvas of size with gutter pixels between
+// slots and border pixels around the edge. Slot sizes differ by at most one
+// pixel so the cells exactly fill the canvas.
+func (t template) rects(size image.Point, gutter, border int) ([]image.Rectangle, error) {
+	xs, err := slotEdges(size.X, t.cols, gutter, border)
+	if err != nil {
+		return nil, err
+	}
+	ys, err := slotEdges(size.Y, t.rows, gutter, border)
+	if err != nil {
+		return nil, err
+	}
+	rects := make([]image.Rectangle, len(t.cells))
+	for i, c := range t.cells {
+		rects[i] = image.Rect(xs[c.col][0], ys[c.row][0], xs[c.col+c.cols-1][1], ys[c.row+c.rows-1][1])
+	}
+	return rects, nil
+}
+
+// slotEdges returns the start and end of n slots along a side of length.
+func slotEdges(length, n, gutter, border int) ([][2]int, error) {
+	inner := length - 2*border - (n-1)*gutter
+	if inner < n {
+		return nil, fmt.Errorf("%d slots with gutter %d and border %d do not fit in %dpx", n, gutter, border, length)
+	}
+	edges := make([][2]int, n)
+	for i := range edges {
+		start := border + i*gutter + i*inner/n
+		edges[i] = [2]int{start, start + (i+1)*inner/n - i*inner/n}
+	}
+	return edges, nil
+}
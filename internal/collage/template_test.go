@@ -0,0 +1,74 @@
+package collage
+
+import (
+	"image"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	t.Parallel()
+
+	tpl, err := parseTemplate("hero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.cols != 4 || tpl.rows != 2 || len(tpl.cells) != 5 {
+		t.Fatalf("expected a 4x2 template with 5 cells, got %dx%d with %d", tpl.cols, tpl.rows, len(tpl.cells))
+	}
+	if hero := tpl.cells[0]; hero != (cell{col: 0, row: 0, cols: 2, rows: 2}) {
+		t.Fatalf("expected the hero to span 2x2 at the origin, got %+v", hero)
+	}
+
+	// Cells are filled in symbol order, not reading order.
+	tpl, err = parseTemplate("BA/C.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpl.cells) != 3 || tpl.cells[0].col != 1 || tpl.cells[1].col != 0 {
+		t.Fatalf("unexpected cells %+v", tpl.cells)
+	}
+
+	for _, bad := range []string{"AB/A", "AB/BA", "A.A", "..", ""} {
+		if _, err := parseTemplate(bad); err == nil {
+			t.Fatalf("expected error for template %q", bad)
+		}
+	}
+}
+
+func TestTemplateRects_FillCanvas(t *testing.T) {
+	t.Parallel()
+
+	tpl, _ := parseTemplate("feature")
+	size := image.Pt(1001, 757)
+	gutter, border := 7, 11
+	rects, err := tpl.rects(size, gutter, border)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	area := 0
+	for i, r := range rects {
+		if !r.In(image.Rect(border, border, size.X-border, size.Y-border)) {
+			t.Fatalf("cell %d %v leaves the border", i, r)
+		}
+		for j := i + 1; j < len(rects); j++ {
+			if inter := r.Intersect(rects[j]); !inter.Empty() {
+				t.Fatalf("cells %d and %d overlap", i, j)
+			}
+		}
+		area += r.Dx() * r.Dy()
+	}
+	// The 3x3 grid loses two gutters each way to the spacing.
+	innerW, innerH := size.X-2*border-2*gutter, size.Y-2*border-2*gutter
+	hero := rects[0]
+	if hero.Dx() != innerW*2/3+gutter || hero.Dy() != innerH*2/3+gutter {
+		t.Fatalf("expected the hero to span two slots and a gutter, got %v", hero)
+	}
+	if rects[5].Max != image.Pt(size.X-border, size.Y-border) {
+		t.Fatalf("expected the last cell to end at the border, got %v", rects[5])
+	}
+
+	if _, err := tpl.rects(image.Pt(20, 20), 10, 0); err == nil {
+		t.Fatalf("expected error when gutters leave no room")
+	}
+}
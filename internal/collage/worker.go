@@ -1,102 +1,216 @@
 package collage
 
 import (
+	"fmt"
 	"handytools/pkg/common"
+	"handytools/pkg/exif"
+	"handytools/pkg/pdf"
+	"handytools/pkg/smartcrop"
 	"image"
 	"image/color"
+	"image/draw"
 	"math"
-	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/disintegration/imaging"
 )
 
-func scaleImages(images []image.Image, width, height int) []image.Image {
-	for i, img := range images {
-		images[i] = imaging.Fill(img, width, height, imaging.Center, imaging.Lanczos)
-	}
-	return images
-}
+// cropFaceRect crops around the face regions of the focus sidecar.
+const cropFaceRect = "face-rect"
+
+// Overflow policies for more images than template cells.
+const (
+	overflowError = "error"
+	overflowPages = "pages"
+)
 
-func createGrid(rows, cols, width, height, frame int) *image.NRGBA {
-	return imaging.New(width*cols+(cols-1)*frame, height*rows+(rows-1)*frame, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+// fillCell crops img to w x h. Images listed in focus are centred on their
+// focus; the rest are cropped by crop, with face-rect falling back to
+// attention.
+func fillCell(img image.Image, path string, w, h int, crop string, focus smartcrop.FocusMap) *image.NRGBA {
+	if f, ok := focus.Lookup(path); ok {
+		return smartcrop.FillAt(img, w, h, f)
+	}
+	if crop == cropFaceRect {
+		logger.Warn("No face region for " + path + ", using attention crop")
+		return smartcrop.FillWith(img, w, h, smartcrop.Attention)
+	}
+	return smartcrop.FillWith(img, w, h, smartcrop.Strategy(crop))
 }
 
-func placeImagesInGrid(grid *image.NRGBA, images []image.Image, rows, cols, width, height, frame int) *image.NRGBA {
-	i := 0
-	for y := 0; y < rows; y++ {
-		for x := 0; x < cols; x++ {
-			if i >= len(images) {
-				break
+// roundCorners fades the corners of img outside a radius r quarter circle to
+// transparent, anti-aliased over one pixel.
+func roundCorners(img *image.NRGBA, r int) {
+	b := img.Bounds()
+	r = min(r, b.Dx()/2, b.Dy()/2)
+	if r <= 0 {
+		return
+	}
+	rf := float64(r)
+	for dy := 0; dy < r; dy++ {
+		for dx := 0; dx < r; dx++ {
+			// Distance of the pixel centre from the corner circle's centre.
+			d := math.Hypot(rf-float64(dx)-0.5, rf-float64(dy)-0.5)
+			coverage := math.Min(math.Max(rf-d+0.5, 0), 1)
+			if coverage == 1 {
+				continue
+			}
+			for _, p := range []image.Point{
+				{b.Min.X + dx, b.Min.Y + dy},
+				{b.Max.X - 1 - dx, b.Min.Y + dy},
+				{b.Min.X + dx, b.Max.Y - 1 - dy},
+				{b.Max.X - 1 - dx, b.Max.Y - 1 - dy},
+			} {
+				c := img.NRGBAAt(p.X, p.Y)
+				c.A = uint8(math.Round(float64(c.A) * coverage))
+				img.SetNRGBA(p.X, p.Y, c)
 			}
-			grid = imaging.Paste(grid, images[i], image.Pt(x*(width+frame), y*(height+frame)))
-			i++
 		}
 	}
-	return grid
 }
 
-func createCollage(cfg Config) {
-	logger := common.GetLogger()
-
-	var images []image.Image
-	for _, imgPath := range cfg.InputFiles {
-		logger.Info("Opening image file: " + imgPath)
-		infile, err := os.Open(imgPath)
-		if err != nil {
-			logger.Error("Failed to open image file: " + imgPath)
-			return
+// canvasSize works out the canvas from --width and --height, filling in a
+// missing side from the aspect ratio. Without either, the canvas is
+// defaultCanvasWidth wide; with a free aspect the height then gives every
+// slot the median aspect ratio of the images.
+func canvasSize(cfg Config, t template, aspect float64, images []image.Point) image.Point {
+	size := image.Pt(cfg.Width, cfg.Height)
+	if size.X > 0 && size.Y > 0 {
+		return size
+	}
+	if aspect > 0 {
+		if size.Y > 0 {
+			return image.Pt(int(math.Round(float64(size.Y)*aspect)), size.Y)
 		}
-		img, _, err := image.Decode(infile)
-		infile.Close()
-		if err != nil {
-			logger.Error("Failed to decode image file: " + imgPath)
-			return
+		if size.X <= 0 {
+			size.X = defaultCanvasWidth
 		}
-		images = append(images, img)
-		logger.Info("Image file loaded: " + imgPath)
+		return image.Pt(size.X, int(math.Round(float64(size.X)/aspect)))
 	}
 
-	if len(images) == 0 {
-		logger.Error("No input images provided")
-		return
+	frame := func(n int) int { return (n-1)*cfg.Gutter + 2*cfg.Border }
+	slot := medianAspect(images)
+	if size.Y > 0 {
+		slotH := float64(size.Y-frame(t.rows)) / float64(t.rows)
+		return image.Pt(frame(t.cols)+int(math.Round(slotH*slot*float64(t.cols))), size.Y)
 	}
+	if size.X <= 0 {
+		size.X = defaultCanvasWidth
+	}
+	slotW := float64(size.X-frame(t.cols)) / float64(t.cols)
+	return image.Pt(size.X, frame(t.rows)+int(math.Round(slotW/slot*float64(t.rows))))
+}
 
-	refWidth := images[0].Bounds().Dx()
-	refHeight := images[0].Bounds().Dy()
+func createCollage(cfg Config) error {
+	t := gridTemplate(cfg.Columns, cfg.Rows)
+	if cfg.Template != "" {
+		var err error
+		if t, err = parseTemplate(cfg.Template); err != nil {
+			return err
+		}
+	}
+	if t.cols <= 0 || t.rows <= 0 {
+		return fmt.Errorf("collage needs at least one row and column")
+	}
+	if len(cfg.InputFiles) == 0 {
+		return fmt.Errorf("no input images provided")
+	}
+	perPage := len(t.cells)
+	if len(cfg.InputFiles) > perPage && cfg.Overflow != overflowPages {
+		return fmt.Errorf("%d images for %d cells; use fewer images or --overflow pages", len(cfg.InputFiles), perPage)
+	}
 
-	maxTotalWidth := 2 * refWidth
-	maxTotalHeight := 2 * refHeight
+	bg, err := parseGutterColor(cfg)
+	if err != nil {
+		return err
+	}
 
-	cellWidth := int(math.Min(float64(refWidth), float64(maxTotalWidth/cfg.Columns)))
-	cellHeight := int(math.Min(float64(refHeight), float64(maxTotalHeight/cfg.Rows)))
+	var focus smartcrop.FocusMap
+	if cfg.FocusFile != "" {
+		if focus, err = smartcrop.ReadFocusFile(cfg.FocusFile); err != nil {
+			return fmt.Errorf("failed to read focus file: %w", err)
+		}
+	}
 
-	if cfg.AspectRatio == "4x5" {
-		totalWidth := cellWidth * cfg.Columns
-		totalHeight := int(float64(totalWidth) * 5.0 / 4.0)
-		cellHeight = totalHeight / cfg.Rows
+	aspect, err := parseAspect(cfg.AspectRatio)
+	if err != nil {
+		return err
+	}
+	var sizes []image.Point
+	for _, imgPath := range cfg.InputFiles {
+		size, err := exif.ImageSize(imgPath)
+		if err != nil {
+			return fmt.Errorf("failed to read image file %s: %w", imgPath, err)
+		}
+		sizes = append(sizes, size)
+	}
+	size := canvasSize(cfg, t, aspect, sizes)
+	rects, err := t.rects(size, cfg.Gutter, cfg.Border)
+	if err != nil {
+		return err
 	}
+	logger.Infof("Canvas %dx%d with %d cells", size.X, size.Y, perPage)
 
-	images = scaleImages(images, cellWidth, cellHeight)
-	logger.Info("Images scaled")
+	var doc *pdf.File
+	if cfg.PDF != "" {
+		if doc, err = pdf.Create(cfg.PDF); err != nil {
+			return err
+		}
+		defer doc.Close()
+	}
 
-	grid := createGrid(cfg.Rows, cfg.Columns, cellWidth, cellHeight, 0)
-	logger.Info("Grid created")
+	ext := filepath.Ext(cfg.OutputFile)
+	prefix := strings.TrimSuffix(cfg.OutputFile, ext)
+	pages := (len(cfg.InputFiles) + perPage - 1) / perPage
+	for p := 0; p < pages; p++ {
+		batch := cfg.InputFiles[p*perPage : min((p+1)*perPage, len(cfg.InputFiles))]
+		canvas := imaging.New(size.X, size.Y, bg)
+		for i, imgPath := range batch {
+			logger.Info("Opening image file: " + imgPath)
+			img, err := imaging.Open(imgPath, imaging.AutoOrientation(true))
+			if err != nil {
+				return fmt.Errorf("failed to open image file %s: %w", imgPath, err)
+			}
+			r := rects[i]
+			tile := fillCell(img, imgPath, r.Dx(), r.Dy(), cfg.Crop, focus)
+			roundCorners(tile, cfg.Radius)
+			draw.Draw(canvas, r, tile, image.Point{}, draw.Over)
+		}
+		logger.Info("Images placed in grid")
 
-	grid = placeImagesInGrid(grid, images, cfg.Rows, cfg.Columns, cellWidth, cellHeight, 0)
-	logger.Info("Images placed in grid")
+		out := ""
+		if cfg.OutputFile != "" {
+			out = cfg.OutputFile
+			if pages > 1 {
+				out = fmt.Sprintf("%s_%02d%s", prefix, p+1, ext)
+			}
+		}
+		if err := pdf.SavePage(canvas, out, doc, cfg.DPI, 95); err != nil {
+			return fmt.Errorf("failed to write page %d: %w", p+1, err)
+		}
+		if out != "" {
+			logger.Info("Merged image saved successfully: " + out)
+		}
+	}
 
-	logger.Info("Writing output file: " + cfg.OutputFile)
-	outfile, err := os.Create(cfg.OutputFile)
-	if err != nil {
-		logger.Error("Failed to create output file: " + cfg.OutputFile)
-		return
+	if doc != nil {
+		if err := doc.Close(); err != nil {
+			return fmt.Errorf("failed to write PDF %s: %w", cfg.PDF, err)
+		}
+		logger.Info("PDF saved successfully: " + cfg.PDF)
 	}
-	defer outfile.Close()
+	return nil
+}
 
-	err = imaging.Encode(outfile, grid, imaging.JPEG)
+// parseGutterColor reads --gutter-color; transparency is only kept by PNG.
+func parseGutterColor(cfg Config) (color.NRGBA, error) {
+	bg, err := common.ParseColor(cfg.GutterColor)
 	if err != nil {
-		logger.Error("Failed to write output file: " + cfg.OutputFile)
-		return
+		return bg, err
+	}
+	if bg.A < 255 && cfg.OutputFile != "" && !strings.EqualFold(filepath.Ext(cfg.OutputFile), ".png") {
+		return bg, fmt.Errorf("a transparent gutter colour needs a .png output")
 	}
-	logger.Info("Merged image saved successfully: " + cfg.OutputFile)
+	return bg, nil
 }
@@ -0,0 +1,203 @@
+package collage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"handytools/pkg/exif"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func writeImages(t *testing.T, n, w, h int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < n; i++ {
+		p := filepath.Join(dir, fmt.Sprintf("in_%d.jpg", i))
+		if err := imaging.Save(imaging.New(w, h, color.NRGBA{200, 30, 30, 255}), p); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+func baseConfig(inputs []string, out string) Config {
+	return Config{
+		InputFiles:  inputs,
+		OutputFile:  out,
+		Rows:        1,
+		Columns:     2,
+		GutterColor: "white",
+		Overflow:    overflowError,
+		AspectRatio: "free",
+		Width:       400,
+		Crop:        "center",
+		DPI:         150,
+	}
+}
+
+func TestCanvasSize(t *testing.T) {
+	t.Parallel()
+
+	grid := gridTemplate(2, 2)
+	images := []image.Point{{400, 300}, {300, 400}, {800, 600}}
+	tests := []struct {
+		name          string
+		width, height int
+		aspect        float64
+		want          image.Point
+	}{
+		{"exact size", 1080, 1350, 0, image.Pt(1080, 1350)},
+		{"aspect from default width", 0, 0, 4.0 / 5, image.Pt(2048, 2560)},
+		{"aspect from width", 1000, 0, 2, image.Pt(1000, 500)},
+		{"aspect from height", 0, 900, 16.0 / 9, image.Pt(1600, 900)},
+		// Free: 20 border, 10 gutter; slots take the median 4:3.
+		{"free from width", 850, 0, 0, image.Pt(850, 650)},
+		{"free from height", 0, 650, 0, image.Pt(850, 650)},
+	}
+	for _, tt := range tests {
+		cfg := Config{Width: tt.width, Height: tt.height, Gutter: 10, Border: 20}
+		if got := canvasSize(cfg, grid, tt.aspect, images); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// The first image no longer decides the canvas.
+	cfg := Config{}
+	if got := canvasSize(cfg, grid, 0, []image.Point{{100, 1000}, {400, 300}, {400, 300}}); got.X != defaultCanvasWidth {
+		t.Fatalf("expected the default width, got %v", got)
+	}
+}
+
+// writeOrientedJPEG writes a w x h JPEG whose EXIF orientation tag is o.
+func writeOrientedJPEG(t *testing.T, w, h, o int) string {
+	t.Helper()
+	var img bytes.Buffer
+	if err := jpeg.Encode(&img, image.NewGray(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// Big-endian TIFF with one IFD0 entry: Orientation, SHORT, count 1.
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01")
+	tiff = append(tiff, byte(o>>8), byte(o), 0, 0, 0, 0, 0, 0)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	var out bytes.Buffer
+	out.Write([]byte{0xff, 0xd8, 0xff, 0xe1})
+	binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(img.Bytes()[2:])
+	p := filepath.Join(t.TempDir(), fmt.Sprintf("oriented_%d.jpg", o))
+	if err := os.WriteFile(p, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestCanvasSize_RotatedOrientation(t *testing.T) {
+	t.Parallel()
+
+	// Landscape pixels tagged as rotated are portrait slots on the canvas.
+	cfg := baseConfig(nil, "")
+	size := canvasSize(cfg, gridTemplate(2, 1), 0, []image.Point{mustProbe(t, writeOrientedJPEG(t, 60, 40, 6))})
+	if size.Y <= size.X/2 {
+		t.Fatalf("expected portrait slots, got canvas %v", size)
+	}
+}
+
+func mustProbe(t *testing.T, path string) image.Point {
+	t.Helper()
+	size, err := exif.ImageSize(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return size
+}
+
+func TestParseAspect(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]float64{
+		"free":  0,
+		"4x5":   0.8,
+		"16:9":  16.0 / 9,
+		"Story": 9.0 / 16,
+		"A4":    0.7071,
+	}
+	for in, want := range tests {
+		got, err := parseAspect(in)
+		if err != nil || math.Abs(got-want) > 1e-4 {
+			t.Errorf("parseAspect(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"wide", "0:1", "4:", "3/2"} {
+		if _, err := parseAspect(bad); err == nil {
+			t.Errorf("expected error for aspect %q", bad)
+		}
+	}
+
+	if size, err := parseSize("1080x1350"); err != nil || size != image.Pt(1080, 1350) {
+		t.Fatalf("parseSize = %v, %v", size, err)
+	}
+	if _, err := parseSize("1080.5x10"); err == nil {
+		t.Fatalf("expected error for fractional size")
+	}
+}
+
+func TestRoundCorners(t *testing.T) {
+	t.Parallel()
+
+	img := imaging.New(40, 40, color.NRGBA{0, 0, 0, 255})
+	roundCorners(img, 10)
+	if a := img.NRGBAAt(0, 0).A; a != 0 {
+		t.Fatalf("expected a transparent corner, got alpha %d", a)
+	}
+	if a := img.NRGBAAt(39, 39).A; a != 0 {
+		t.Fatalf("expected a transparent opposite corner, got alpha %d", a)
+	}
+	if a := img.NRGBAAt(20, 0).A; a != 255 {
+		t.Fatalf("expected the edge middle untouched, got alpha %d", a)
+	}
+}
+
+func TestCreateCollage_Overflow(t *testing.T) {
+	t.Parallel()
+
+	inputs := writeImages(t, 5, 200, 100)
+	out := filepath.Join(t.TempDir(), "collage.jpg")
+
+	cfg := baseConfig(inputs, out)
+	if err := createCollage(cfg); err == nil {
+		t.Fatalf("expected error for 5 images in 2 cells")
+	}
+
+	cfg.Overflow = overflowPages
+	cfg.Gutter, cfg.Border = 8, 8
+	if err := createCollage(cfg); err != nil {
+		t.Fatalf("createCollage: %v", err)
+	}
+	pages, _ := filepath.Glob(filepath.Join(filepath.Dir(out), "collage_*.jpg"))
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 numbered pages, got %v", pages)
+	}
+	// The last page has one image; its empty cell shows the gutter colour.
+	last, err := imaging.Open(pages[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := last.Bounds()
+	if r, _, _, _ := last.At(b.Dx()*3/4, b.Dy()/2).RGBA(); r>>8 < 240 {
+		t.Fatalf("expected an empty white cell on the last page")
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Fatalf("expected only numbered pages")
+	}
+}
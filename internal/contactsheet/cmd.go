@@ -0,0 +1,73 @@
+package contactsheet
+
+import (
+	"handytools/pkg/common"
+	"handytools/pkg/pdf"
+
+	"github.com/spf13/cobra"
+)
+
+type Config struct {
+	InputFiles []string
+	OutputFile string
+	Paper      string  // "a4" or "letter"
+	Landscape  bool    // Turn the paper sideways
+	DPI        int     // Print resolution
+	Grid       string  // Columns x rows, e.g. "4x5"
+	Margin     float64 // Page margin in millimetres
+	Gap        float64 // Space between cells in millimetres
+	FontSize   float64 // Label size in points
+	Title      string  // Printed at the top of every page
+	PDF        string  // Write all pages into this PDF
+}
+
+var (
+	logger = common.GetLogger()
+	config Config
+)
+
+var Cmd = &cobra.Command{
+	Use:   "contactsheet",
+	Short: "Create print-ready contact sheets",
+	Long: `Lays images out as thumbnails in a fixed grid on A4 or Letter pages at the
+given DPI, each labelled with its file name and the shutter speed, aperture and
+ISO from its EXIF data. Pages are numbered; use --pdf to send them as one PDF.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			logger.Error("No images provided. Use wildcard or file list.")
+			return
+		}
+		config.InputFiles = common.ExpandWildcards(args)
+		if len(config.InputFiles) == 0 {
+			logger.Error("No images match the given patterns")
+			return
+		}
+		if _, err := pdf.ParsePaper(config.Paper); err != nil {
+			logger.WithError(err).Error("Invalid paper")
+			return
+		}
+		if _, _, err := parseGrid(config.Grid); err != nil {
+			logger.WithError(err).Error("Invalid grid")
+			return
+		}
+		if config.PDF != "" && !cmd.Flags().Changed("output") {
+			config.OutputFile = ""
+		}
+		if err := createContactSheets(config); err != nil {
+			logger.WithError(err).Error("Failed to create contact sheets")
+		}
+	},
+}
+
+func init() {
+	Cmd.Flags().StringVarP(&config.OutputFile, "output", "o", "contactsheet.jpg", "Output file; several pages are numbered (contactsheet_01.jpg, ...)")
+	Cmd.Flags().StringVar(&config.Paper, "paper", "a4", "Paper size: a4 or letter")
+	Cmd.Flags().BoolVar(&config.Landscape, "landscape", false, "Use the paper in landscape orientation")
+	Cmd.Flags().IntVar(&config.DPI, "dpi", 300, "Print resolution in dots per inch")
+	Cmd.Flags().StringVarP(&config.Grid, "grid", "g", "4x5", "Thumbnails per page as columns x rows")
+	Cmd.Flags().Float64Var(&config.Margin, "margin", 10, "Page margin in millimetres")
+	Cmd.Flags().Float64Var(&config.Gap, "gap", 4, "Space between thumbnails in millimetres")
+	Cmd.Flags().Float64Var(&config.FontSize, "font-size", 7, "Label size in points")
+	Cmd.Flags().StringVar(&config.Title, "title", "", "Title printed at the top of every page")
+	Cmd.Flags().StringVar(&config.PDF, "pdf", "", "Write all pages into one PDF; image files are only written as well when --output is given")
+}
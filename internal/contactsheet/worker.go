@@ -0,0 +1,232 @@
+package contactsheet
+
+import (
+	"fmt"
+	"handytools/pkg/exif"
+	"handytools/pkg/label"
+	"handytools/pkg/pdf"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+var (
+	paperWhite = color.NRGBA{255, 255, 255, 255}
+	ink        = color.NRGBA{0, 0, 0, 255}
+	faint      = color.NRGBA{96, 96, 96, 255}
+)
+
+// sheet is the pixel geometry of one page.
+type sheet struct {
+	paper      pdf.Size
+	size       image.Point
+	cols, rows int
+	margin     int
+	gap        int
+	fontPx     int
+	line       int // Height of one label line
+	header     int // Title band, 0 without a title
+	footer     int // Page number band
+}
+
+func parseGrid(s string) (int, int, error) {
+	c, r, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	cols, err1 := strconv.Atoi(c)
+	rows, err2 := strconv.Atoi(r)
+	if !ok || err1 != nil || err2 != nil || cols <= 0 || rows <= 0 {
+		return 0, 0, fmt.Errorf("grid %q is not columns x rows, e.g. 4x5", s)
+	}
+	return cols, rows, nil
+}
+
+func newSheet(cfg Config) (sheet, error) {
+	paper, err := pdf.ParsePaper(cfg.Paper)
+	if err != nil {
+		return sheet{}, err
+	}
+	if cfg.Landscape {
+		paper = paper.Landscape()
+	}
+	if cfg.DPI <= 0 {
+		return sheet{}, fmt.Errorf("dpi must be positive, got %d", cfg.DPI)
+	}
+	cols, rows, err := parseGrid(cfg.Grid)
+	if err != nil {
+		return sheet{}, err
+	}
+
+	mm := func(v float64) int { return int(math.Round(v / 25.4 * float64(cfg.DPI))) }
+	s := sheet{
+		paper:  paper,
+		size:   paper.Pixels(cfg.DPI),
+		cols:   cols,
+		rows:   rows,
+		margin: mm(cfg.Margin),
+		gap:    mm(cfg.Gap),
+		fontPx: max(int(math.Round(cfg.FontSize*float64(cfg.DPI)/72)), 6),
+	}
+	s.line = label.LineHeight(s.fontPx)
+	s.footer = s.line
+	if cfg.Title != "" {
+		s.header = label.LineHeight(s.fontPx * 2)
+	}
+
+	cell := s.cell(0)
+	if cell.Dx() <= 0 || cell.Dy()-2*s.line <= 0 {
+		return sheet{}, fmt.Errorf("a %dx%d grid leaves no room for thumbnails on this page", cols, rows)
+	}
+	return s, nil
+}
+
+func (s sheet) perPage() int {
+	return s.cols * s.rows
+}
+
+// cell is the rectangle of the i-th slot on a page: the thumbnail box with
+// two label lines below it.
+func (s sheet) cell(i int) image.Rectangle {
+	areaW := s.size.X - 2*s.margin
+	areaH := s.size.Y - 2*s.margin - s.header - s.footer
+	w := (areaW - (s.cols-1)*s.gap) / s.cols
+	h := (areaH - (s.rows-1)*s.gap) / s.rows
+	col, row := i%s.cols, i/s.cols
+	x := s.margin + col*(w+s.gap)
+	y := s.margin + s.header + row*(h+s.gap)
+	return image.Rect(x, y, x+w, y+h)
+}
+
+func createContactSheets(cfg Config) error {
+	s, err := newSheet(cfg)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, path := range cfg.InputFiles {
+		if _, err := exif.ImageSize(path); err != nil {
+			logger.WithError(err).Warn("Skipping image: ", path)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no valid images")
+	}
+
+	ext := filepath.Ext(cfg.OutputFile)
+	prefix := strings.TrimSuffix(cfg.OutputFile, ext)
+	pages := (len(paths) + s.perPage() - 1) / s.perPage()
+
+	var doc *pdf.File
+	if cfg.PDF != "" {
+		if doc, err = pdf.Create(cfg.PDF); err != nil {
+			return err
+		}
+		defer doc.Close()
+	}
+
+	for p := 0; p < pages; p++ {
+		batch := paths[p*s.perPage() : min((p+1)*s.perPage(), len(paths))]
+		canvas := renderSheet(s, batch, cfg.Title, p+1, pages)
+
+		if doc != nil {
+			if err := doc.AddImage(canvas, s.paper, 92); err != nil {
+				return fmt.Errorf("failed to add page %d: %w", p+1, err)
+			}
+			logger.Infof("Added page %d/%d", p+1, pages)
+		}
+		if cfg.OutputFile == "" {
+			continue
+		}
+		out := cfg.OutputFile
+		if pages > 1 {
+			out = fmt.Sprintf("%s_%02d%s", prefix, p+1, ext)
+		}
+		if err := imaging.Save(canvas, out); err != nil {
+			return fmt.Errorf("failed to save page %d: %w", p+1, err)
+		}
+		logger.Info("Saved page: " + out)
+	}
+
+	if doc != nil {
+		if err := doc.Close(); err != nil {
+			return err
+		}
+		logger.Info("Saved PDF: " + cfg.PDF)
+	}
+	return nil
+}
+
+// renderSheet draws one page, decoding each image only while its thumbnail
+// is made.
+func renderSheet(s sheet, paths []string, title string, page, pages int) *image.NRGBA {
+	canvas := imaging.New(s.size.X, s.size.Y, paperWhite)
+	face := label.NewFace(s.fontPx)
+	defer face.Close()
+
+	for i, path := range paths {
+		cell := s.cell(i)
+		box := cell
+		box.Max.Y -= 2 * s.line
+
+		img, err := imaging.Open(path, imaging.AutoOrientation(true))
+		if err != nil {
+			logger.WithError(err).Warn("Failed to open image: ", path)
+		} else {
+			thumb := imaging.Fit(img, box.Dx(), box.Dy(), imaging.Lanczos)
+			tb := thumb.Bounds()
+			at := image.Pt(box.Min.X+(box.Dx()-tb.Dx())/2, box.Max.Y-tb.Dy())
+			draw.Draw(canvas, tb.Add(at), thumb, tb.Min, draw.Src)
+		}
+
+		name := image.Rect(cell.Min.X, box.Max.Y, cell.Max.X, box.Max.Y+s.line)
+		label.Draw(canvas, name, filepath.Base(path), face, ink)
+		if summary := exposureSummary(path); summary != "" {
+			label.Draw(canvas, name.Add(image.Pt(0, s.line)), summary, face, faint)
+		}
+	}
+
+	if title != "" {
+		titleFace := label.NewFace(s.fontPx * 2)
+		defer titleFace.Close()
+		header := image.Rect(s.margin, s.margin, s.size.X-s.margin, s.margin+s.header)
+		label.Draw(canvas, header, title, titleFace, ink)
+	}
+	bottom := s.size.Y - s.margin
+	footer := image.Rect(s.margin, bottom-s.footer, s.size.X-s.margin, bottom)
+	label.Draw(canvas, footer, fmt.Sprintf("Page %d of %d", page, pages), face, faint)
+	return canvas
+}
+
+// exposureSummary formats shutter speed, aperture and ISO, e.g.
+// "1/250s · f/2.8 · ISO 400", leaving out whatever the file does not record.
+func exposureSummary(path string) string {
+	d, err := exif.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var parts []string
+	if num, den, ok := d.Rational(exif.ExposureTime); ok && num > 0 {
+		parts = append(parts, formatShutter(num, den))
+	}
+	if num, den, ok := d.Rational(exif.FNumber); ok && num > 0 {
+		parts = append(parts, "f/"+strconv.FormatFloat(float64(num)/float64(den), 'f', -1, 64))
+	}
+	if iso, ok := d.Int(exif.ISO); ok && iso > 0 {
+		parts = append(parts, "ISO "+strconv.Itoa(iso))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func formatShutter(num, den uint32) string {
+	if num >= den {
+		return strconv.FormatFloat(float64(num)/float64(den), 'f', -1, 64) + "s"
+	}
+	return fmt.Sprintf("1/%ds", int(math.Round(float64(den)/float64(num))))
+}
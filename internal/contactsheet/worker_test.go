@@ -0,0 +1,63 @@
+package contactsheet
+
+import (
+	"image"
+	"testing"
+)
+
+func TestParseGrid(t *testing.T) {
+	t.Parallel()
+
+	if c, r, err := parseGrid("4X6"); err != nil || c != 4 || r != 6 {
+		t.Fatalf("expected 4x6, got %dx%d (%v)", c, r, err)
+	}
+	for _, bad := range []string{"4", "0x3", "ax2", ""} {
+		if _, _, err := parseGrid(bad); err == nil {
+			t.Fatalf("expected error for grid %q", bad)
+		}
+	}
+}
+
+func TestFormatShutter(t *testing.T) {
+	t.Parallel()
+
+	tests := map[[2]uint32]string{
+		{1, 250}:  "1/250s",
+		{10, 600}: "1/60s",
+		{2, 1}:    "2s",
+		{5, 2}:    "2.5s",
+	}
+	for in, want := range tests {
+		if got := formatShutter(in[0], in[1]); got != want {
+			t.Errorf("formatShutter(%d/%d) = %q, want %q", in[0], in[1], got, want)
+		}
+	}
+}
+
+func TestSheet_CellsTileThePage(t *testing.T) {
+	t.Parallel()
+
+	s, err := newSheet(Config{Paper: "letter", DPI: 150, Grid: "3x4", Margin: 10, Gap: 4, FontSize: 7, Title: "Proofs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.size != image.Pt(1275, 1650) {
+		t.Fatalf("expected Letter at 150 dpi to be 1275x1650, got %v", s.size)
+	}
+	page := image.Rect(s.margin, s.margin+s.header, s.size.X-s.margin, s.size.Y-s.margin-s.footer)
+	for i := 0; i < s.perPage(); i++ {
+		c := s.cell(i)
+		if !c.In(page) {
+			t.Fatalf("cell %d %v leaves the printable area %v", i, c, page)
+		}
+		for j := i + 1; j < s.perPage(); j++ {
+			if c.Overlaps(s.cell(j)) {
+				t.Fatalf("cells %d and %d overlap", i, j)
+			}
+		}
+	}
+
+	if _, err := newSheet(Config{Paper: "a4", DPI: 30, Grid: "40x40", FontSize: 7}); err == nil {
+		t.Fatalf("expected error when the grid leaves no room")
+	}
+}
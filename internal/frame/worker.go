@@ -2,30 +2,22 @@ package frame
 
 import (
 	"fmt"
+	"handytools/pkg/common"
 	"image"
 	"image/color"
 	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/disintegration/imaging"
 )
 
-var namedColors = map[string]color.NRGBA{
-	"white": {R: 255, G: 255, B: 255, A: 255},
-	"black": {R: 0, G: 0, B: 0, A: 255},
-	"cream": {R: 255, G: 253, B: 231, A: 255},
-	"ivory": {R: 255, G: 255, B: 240, A: 255},
-}
-
 func runFrame(cfg Config) {
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	fc, err := parseColor(cfg.Color)
+	fc, err := parseFrameColor(cfg.Color)
 	if err != nil {
 		logger.WithError(err).Error("Invalid frame color")
 		return
@@ -45,6 +37,19 @@ func runFrame(cfg Config) {
 	}
 }
 
+// parseFrameColor rejects transparent colours, which the JPEG output would
+// silently flatten.
+func parseFrameColor(s string) (color.NRGBA, error) {
+	fc, err := common.ParseColor(s)
+	if err != nil {
+		return fc, err
+	}
+	if fc.A < 255 {
+		return fc, fmt.Errorf("frames are saved as JPEG and cannot be transparent: %q", s)
+	}
+	return fc, nil
+}
+
 func processFile(inputPath string, cfg Config, fc color.NRGBA, rng *rand.Rand) error {
 	src, err := imaging.Open(inputPath)
 	if err != nil {
@@ -147,21 +152,3 @@ func randomInterp(length, n int, lo, hi float64, rng *rand.Rand) []float64 {
 	}
 	return out
 }
-
-func parseColor(s string) (color.NRGBA, error) {
-	s = strings.ToLower(strings.TrimSpace(s))
-	if c, ok := namedColors[s]; ok {
-		return c, nil
-	}
-	hex := strings.TrimPrefix(s, "#")
-	if len(hex) != 6 {
-		return color.NRGBA{}, fmt.Errorf("unknown color %q — use white/black/cream/ivory or #RRGGBB", s)
-	}
-	r, e1 := strconv.ParseUint(hex[0:2], 16, 8)
-	g, e2 := strconv.ParseUint(hex[2:4], 16, 8)
-	b, e3 := strconv.ParseUint(hex[4:6], 16, 8)
-	if e1 != nil || e2 != nil || e3 != nil {
-		return color.NRGBA{}, fmt.Errorf("invalid hex color %q", s)
-	}
-	return color.NRGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}, nil
-}
@@ -2,21 +2,59 @@ package gallery
 
 import (
 	"bufio"
+	"context"
 	"handytools/pkg/assemble"
 	"handytools/pkg/common"
+	"handytools/pkg/layout"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 type Config struct {
-	BoardURL   string
-	Output     string
-	Directory  string
-	InputList  string
-	FitOnePage bool
+	BoardURL     string
+	Source       string
+	SourceType   string
+	Scraper      string
+	Timeout      time.Duration
+	CacheDir     string
+	SyncDir      string
+	Output       string
+	Directory    string
+	Recursive    bool
+	Exclude      []string
+	Filter       Filter
+	InputList    string
+	Manifest     string
+	Layout       string
+	Columns      int
+	PerPage      int
+	Mode         string
+	RowBreaking  string
+	FitOnePage   bool
+	Width        int
+	Height       int
+	Margin       int
+	Spacing      int
+	Header       int
+	Background   string
+	RowHeight    int
+	MinRowHeight int
+	MaxRowHeight int
+	FillLastPage bool
+	Sort         string
+	Seed         int64
+	GroupBy      string
+	Caption      string
+	CaptionPos   string
+	Title        string
+	Footer       string
+	FontSize     int
+	PDF          string
+	DPI          int
 }
 
 var config Config
@@ -27,31 +65,48 @@ var Cmd = &cobra.Command{
 	Short: "Assemble a gallery image from a list of images or Pinterest board",
 	Long:  "Creates a single or multi-page image gallery from local image paths, a directory, a Pinterest board, or a file containing image paths.",
 	Run: func(cmd *cobra.Command, args []string) {
+		pinOpts, err := pinterestOptions()
+		if err != nil {
+			logger.WithError(err).Error("Invalid scraper")
+			return
+		}
 		var imagePaths []string
+		captions := map[string]string{}
+		var groups map[string]string
 
 		switch {
 		case config.BoardURL != "":
 			logger.Infof("Fetching board: %s", config.BoardURL)
-			tempDir := filepath.Join(os.TempDir(), "pinterest_pins")
-			os.MkdirAll(tempDir, 0755)
-			var err error
-			imagePaths, err = DownloadPins(config.BoardURL, tempDir)
+			imagePaths, err = DownloadPins(config.BoardURL, pinOpts, NewDownloader(config.CacheDir))
 			if err != nil {
 				logger.WithError(err).Error("Failed to download pins")
 				return
 			}
+		case config.Source != "":
+			src, err := NewSource(config.Source, config.SourceType, pinOpts)
+			if err != nil {
+				logger.WithError(err).Error("Invalid source")
+				return
+			}
+			imagePaths, err = Download(context.Background(), src, NewDownloader(config.CacheDir))
+			if err != nil {
+				logger.WithError(err).Error("Failed to download images")
+				return
+			}
 		case config.Directory != "":
 			logger.Infof("Loading images from directory: %s", config.Directory)
-			entries, err := os.ReadDir(config.Directory)
+			imagePaths, err = ListDirectory(config.Directory, config.Recursive, config.Exclude)
 			if err != nil {
 				logger.WithError(err).Error("Failed to read directory")
 				return
 			}
-			for _, e := range entries {
-				if !e.IsDir() && strings.HasSuffix(e.Name(), ".jpg") {
-					imagePaths = append(imagePaths, filepath.Join(config.Directory, e.Name()))
-				}
+		case config.Manifest != "":
+			in, err := LoadManifest(config.Manifest)
+			if err != nil {
+				logger.WithError(err).Error("Failed to read manifest")
+				return
 			}
+			imagePaths, captions, groups = in.Paths, in.Captions, in.Sections
 		case config.InputList != "":
 			file, err := os.Open(config.InputList)
 			if err != nil {
@@ -61,10 +116,14 @@ var Cmd = &cobra.Command{
 			defer file.Close()
 			scanner := bufio.NewScanner(file)
 			for scanner.Scan() {
-				line := strings.TrimSpace(scanner.Text())
-				line = strings.Trim(line, `"`)
-				if line != "" {
-					imagePaths = append(imagePaths, line)
+				path, caption, _ := strings.Cut(scanner.Text(), "\t")
+				path = strings.Trim(strings.TrimSpace(path), `"`)
+				if path == "" {
+					continue
+				}
+				imagePaths = append(imagePaths, path)
+				if caption = strings.TrimSpace(caption); caption != "" {
+					captions[path] = caption
 				}
 			}
 			if err := scanner.Err(); err != nil {
@@ -73,7 +132,7 @@ var Cmd = &cobra.Command{
 			}
 		default:
 			if len(args) == 0 {
-				logger.Error("No input source provided. Use --pinterest, --directory, --file or pass image paths as arguments.")
+				logger.Error("No input source provided. Use --pinterest, --source, --directory, --file, --manifest or pass image paths as arguments.")
 				return
 			}
 			imagePaths = args
@@ -84,18 +143,232 @@ var Cmd = &cobra.Command{
 			return
 		}
 
-		output := strings.TrimSuffix(config.Output, ".jpg")
-		err := assemble.AssembleImagesWithMax(imagePaths, output, config.FitOnePage)
+		imagePaths, err = FilterImages(imagePaths, config.Filter)
+		if err != nil {
+			logger.WithError(err).Error("Invalid filter")
+			return
+		}
+		if len(imagePaths) == 0 {
+			logger.Warn("No images left after filtering")
+			return
+		}
+		sortKey, err := assemble.ParseSort(config.Sort)
+		if err != nil {
+			logger.WithError(err).Error("Invalid sort")
+			return
+		}
+		groupBy, err := assemble.ParseGroupBy(config.GroupBy)
+		if err != nil {
+			logger.WithError(err).Error("Invalid grouping")
+			return
+		}
+		imagePaths = assemble.SortImages(imagePaths, sortKey, config.Seed)
+		if groupBy != assemble.GroupNone {
+			groups = assemble.GroupImages(imagePaths, groupBy)
+		}
+
+		mode, err := layoutMode(cmd)
+		if err != nil {
+			logger.WithError(err).Error("Invalid layout mode")
+			return
+		}
+		engine, err := layout.ParseEngine(config.Layout)
+		if err != nil {
+			logger.WithError(err).Error("Invalid layout")
+			return
+		}
+		breaking, err := layout.ParseRowBreaking(config.RowBreaking)
+		if err != nil {
+			logger.WithError(err).Error("Invalid row breaking")
+			return
+		}
+		bg, err := common.ParseColor(config.Background)
+		if err != nil {
+			logger.WithError(err).Error("Invalid background colour")
+			return
+		}
+		caption, err := assemble.ParseCaption(config.Caption)
+		if err != nil {
+			logger.WithError(err).Error("Invalid caption")
+			return
+		}
+		if !cmd.Flags().Changed("caption") && len(captions) > 0 {
+			caption = assemble.CaptionText
+		}
+		captionPos, err := assemble.ParseCaptionPosition(config.CaptionPos)
+		if err != nil {
+			logger.WithError(err).Error("Invalid caption position")
+			return
+		}
+		ext := filepath.Ext(config.Output)
+		opts := assemble.Options{
+			Width:        config.Width,
+			Height:       config.Height,
+			Margin:       config.Margin,
+			Spacing:      config.Spacing,
+			HeaderHeight: config.Header,
+			Background:   bg,
+			RowHeight:    config.RowHeight,
+			MinRowHeight: config.MinRowHeight,
+			MaxRowHeight: config.MaxRowHeight,
+			Engine:       engine,
+			Columns:      config.Columns,
+			PerPage:      config.PerPage,
+			Mode:         mode,
+			Breaking:     breaking,
+			FillLastPage: config.FillLastPage,
+			Format:       ext,
+
+			Caption:         caption,
+			CaptionPosition: captionPos,
+			Captions:        captions,
+			Title:           config.Title,
+			Groups:          groups,
+			Footer:          config.Footer,
+			FontSize:        config.FontSize,
+
+			PDF: config.PDF,
+			DPI: config.DPI,
+		}
+		output := strings.TrimSuffix(config.Output, ext)
+		if config.PDF != "" && !cmd.Flags().Changed("output") {
+			output = ""
+		}
+		err = assemble.AssembleImages(imagePaths, output, opts)
 		if err != nil {
 			logger.WithError(err).Error("Failed to assemble gallery")
 		}
 	},
 }
 
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Mirror a Pinterest board into a folder, downloading only new pins",
+	Long: `Keeps a local copy of a Pinterest board. Pins are saved as pin_<ID> and only
+new pins are downloaded; pins that left the board stay on disk and are marked
+removed in manifest.json, which lists the board in order and can be passed to
+img gallery --manifest.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if config.BoardURL == "" || config.SyncDir == "" {
+			logger.Error("Both --pinterest and --dir are required")
+			return
+		}
+		pinOpts, err := pinterestOptions()
+		if err != nil {
+			logger.WithError(err).Error("Invalid scraper")
+			return
+		}
+		logger.Infof("Syncing board %s into %s", config.BoardURL, config.SyncDir)
+		stats, err := SyncBoard(config.BoardURL, config.SyncDir, pinOpts, NewDownloader(config.CacheDir))
+		if err != nil {
+			logger.WithError(err).Error("Failed to sync board")
+			return
+		}
+		logger.Infof("Sync done: %s", stats)
+	},
+}
+
 func init() {
+	Cmd.AddCommand(syncCmd)
+	syncCmd.Flags().StringVarP(&config.BoardURL, "pinterest", "p", "", "Pinterest board URL")
+	syncCmd.Flags().StringVarP(&config.SyncDir, "dir", "d", "", "Folder holding the mirror and its manifest.json")
+	syncCmd.Flags().StringVar(&config.Scraper, "scraper", ScraperAuto, "How the board is read: auto, http or browser (see img gallery --help)")
+	syncCmd.Flags().DurationVar(&config.Timeout, "timeout", 2*time.Minute, "Time limit for scrolling the board in headless Chrome")
+	syncCmd.Flags().StringVar(&config.CacheDir, "cache-dir", DefaultCacheDir(), "Directory where downloaded images are cached between runs")
+
 	Cmd.Flags().StringVarP(&config.BoardURL, "pinterest", "p", "", "Pinterest board URL")
-	Cmd.Flags().StringVarP(&config.Output, "output", "o", "gallery.jpg", "Output image path prefix (e.g., out/gallery_01.jpg)")
-	Cmd.Flags().StringVarP(&config.Directory, "directory", "d", "", "Directory to read .jpg files from")
-	Cmd.Flags().StringVarP(&config.InputList, "file", "f", "", "Text file with list of image paths")
-	Cmd.Flags().BoolVar(&config.FitOnePage, "fitOnePage", true, "Try to fit images into one page (default true)")
+	Cmd.Flags().StringVarP(&config.Source, "source", "s", "", "Board, feed, web page, HTML file or URL list to download images from")
+	Cmd.Flags().StringVar(&config.SourceType, "source-type", SourceAuto, `How --source is read:
+  auto      = from the location: Pinterest URLs, feed:// or .rss/.atom/.xml feeds, other URLs and .html files as pages, other files as lists
+  pinterest = Pinterest board
+  feed      = RSS or Atom feed with Media RSS, enclosures or <img> in items
+  html      = <img> tags of a web page or saved HTML file
+  list      = text file with one image URL per line`)
+	Cmd.Flags().StringVar(&config.Scraper, "scraper", ScraperAuto, `How Pinterest boards are read:
+  auto    = plain HTTP, falling back to headless Chrome when that finds no pins
+  http    = page data and Pinterest's resource API over plain HTTP, no Chrome needed
+  browser = scroll the board in headless Chrome; pins are not assigned to board sections`)
+	Cmd.Flags().DurationVar(&config.Timeout, "timeout", 2*time.Minute, "Time limit for scrolling a Pinterest board in headless Chrome; pins found by then are kept")
+	Cmd.Flags().StringVar(&config.CacheDir, "cache-dir", DefaultCacheDir(), "Directory where downloaded images are cached between runs")
+	Cmd.Flags().StringVarP(&config.Output, "output", "o", "gallery.jpg", "Output image path prefix, .jpg or .png (e.g., out/gallery_01.jpg)")
+	Cmd.Flags().StringVarP(&config.Directory, "directory", "d", "", "Directory to read images (jpg, png, gif, bmp, tiff, webp) from")
+	Cmd.Flags().BoolVarP(&config.Recursive, "recursive", "r", false, "Also read images in subdirectories of --directory")
+	Cmd.Flags().StringSliceVarP(&config.Exclude, "exclude", "e", []string{}, `Skip files and folders of --directory matching wildcard patterns, relative to it (e.g. "raw/...", "*_edit.jpg", ! to re-include)`)
+	Cmd.Flags().IntVar(&config.Filter.MinWidth, "min-width", 0, "Skip images narrower than this many pixels")
+	Cmd.Flags().IntVar(&config.Filter.MinHeight, "min-height", 0, "Skip images shorter than this many pixels")
+	Cmd.Flags().IntVar(&config.Filter.MaxWidth, "max-width", 0, "Skip images wider than this many pixels, 0 for no limit")
+	Cmd.Flags().IntVar(&config.Filter.MaxHeight, "max-height", 0, "Skip images taller than this many pixels, 0 for no limit")
+	Cmd.Flags().StringVar(&config.Filter.Orientation, "orientation", OrientationAny, "Keep only landscape, portrait or square images")
+	Cmd.Flags().StringVarP(&config.InputList, "file", "f", "", "Text file with one image path per line, optionally followed by a tab and a caption")
+	Cmd.Flags().StringVar(&config.Manifest, "manifest", "", "Manifest written by a board download; pin titles become captions and each board section starts a new page")
+	Cmd.Flags().StringVarP(&config.Layout, "layout", "l", "justified", `Layout engine:
+  justified = rows of equal height
+  masonry   = columns of equal width, images added to the shortest column
+  mixed     = masonry where landscape images span two columns
+  mosaic    = every page filled edge to edge, images cropped to their cells`)
+	Cmd.Flags().IntVar(&config.Columns, "columns", 3, "Column count for masonry and mixed layouts (fit mode adds columns as needed)")
+	Cmd.Flags().IntVar(&config.PerPage, "per-page", 5, "Images per page for the mosaic layout (max 8)")
+	Cmd.Flags().StringVarP(&config.Mode, "mode", "m", "fit", `Layout mode:
+  fit     = all images on one page, rows as large as possible
+  rows    = rows at --row-height, split into pages
+  compact = up to 6 images per row, split into pages
+  medium  = up to 3 images per row, split into pages
+  large   = one image per row, split into pages`)
+	Cmd.Flags().StringVar(&config.RowBreaking, "row-breaking", "greedy", "How images are split into rows: greedy (fast) or optimal (even row heights)")
+	Cmd.Flags().BoolVar(&config.FitOnePage, "fitOnePage", true, "Try to fit images into one page")
+	Cmd.Flags().MarkDeprecated("fitOnePage", "use --mode fit, or --mode rows for --fitOnePage=false")
+	Cmd.Flags().IntVar(&config.Width, "width", 1080, "Page width in pixels")
+	Cmd.Flags().IntVar(&config.Height, "height", 1920, "Maximum page height in pixels")
+	Cmd.Flags().IntVar(&config.Margin, "margin", 0, "Outer margin around each page in pixels")
+	Cmd.Flags().IntVar(&config.Spacing, "spacing", assemble.DefaultSpace, "Space between images in pixels")
+	Cmd.Flags().IntVar(&config.Header, "header", 0, "Space reserved at the top of each page in pixels")
+	Cmd.Flags().StringVar(&config.Background, "background", "white", "Background colour: white, black, cream, ivory, transparent (PNG), #RRGGBB or #RRGGBBAA")
+	Cmd.Flags().IntVar(&config.RowHeight, "row-height", 320, "Target row height in pixels for --mode rows")
+	Cmd.Flags().IntVar(&config.MinRowHeight, "min-row-height", 100, "Smallest row height tried in fit mode")
+	Cmd.Flags().IntVar(&config.MaxRowHeight, "max-row-height", 0, "Largest allowed row height, 0 for no limit")
+	Cmd.Flags().BoolVar(&config.FillLastPage, "fill-last-page", false, "Use taller rows on the last page so it is filled like the others")
+	Cmd.Flags().StringVar(&config.Sort, "sort", "none", `Image order:
+  none       = as given, or as the board or directory lists them
+  name       = file name, numbers in natural order
+  date       = file modification time
+  taken      = EXIF date taken, else modification time
+  aspect     = tallest to widest
+  colour     = by hue of the average colour, greys last
+  random     = shuffled, repeatable with --seed
+  interleave = landscape and portrait alternating, for evenly packed rows`)
+	Cmd.Flags().Int64Var(&config.Seed, "seed", 0, "Random seed for --sort random (0 = random, the seed used is logged)")
+	Cmd.Flags().StringVar(&config.GroupBy, "group-by", "none", `Start a new page, titled with the group, for each:
+  folder      = directory the image is in
+  date        = day taken
+  orientation = landscape, portrait and square images`)
+	Cmd.Flags().StringVar(&config.Caption, "caption", "none", `Caption under each image:
+  none    = no captions
+  name    = file name
+  counter = image number, counting across pages
+  date    = EXIF date taken, else file modification time
+  text    = caption from the --file list (default when the list has captions)`)
+	Cmd.Flags().StringVar(&config.CaptionPos, "caption-position", "below", "Draw captions below the images or over their bottom edge (mosaic is always over)")
+	Cmd.Flags().StringVar(&config.Title, "title", "", "Title at the top of every page; {page}, {pages} and {group} (from --group-by or the board section) are replaced")
+	Cmd.Flags().StringVar(&config.Footer, "footer", "", `Footer at the bottom of every page, e.g. "Page {page} of {pages}"`)
+	Cmd.Flags().IntVar(&config.FontSize, "font-size", 16, "Caption and footer text size in pixels, titles are 1.5x")
+	Cmd.Flags().StringVar(&config.PDF, "pdf", "", "Write all pages into one PDF; image files are only written as well when --output is given")
+	Cmd.Flags().IntVar(&config.DPI, "dpi", 150, "Print resolution that sets the PDF page size")
+}
+
+func pinterestOptions() (PinterestOptions, error) {
+	scraper, err := ParseScraper(config.Scraper)
+	if err != nil {
+		return PinterestOptions{}, err
+	}
+	return PinterestOptions{Scraper: scraper, Timeout: config.Timeout}, nil
+}
+
+// layoutMode resolves --mode, mapping the deprecated --fitOnePage=false onto
+// --mode rows.
+func layoutMode(cmd *cobra.Command) (layout.Mode, error) {
+	if !cmd.Flags().Changed("mode") && cmd.Flags().Changed("fitOnePage") && !config.FitOnePage {
+		return layout.ModeRows, nil
+	}
+	return layout.ParseMode(config.Mode)
 }
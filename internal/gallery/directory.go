@@ -0,0 +1,125 @@
+package gallery
+
+import (
+	"fmt"
+	"handytools/pkg/exif"
+	"handytools/pkg/match"
+	"image"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// imageExts are the file extensions read from directories, lower case.
+var imageExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".bmp": true, ".tif": true, ".tiff": true, ".webp": true,
+}
+
+// Orientations accepted by Filter.Orientation.
+const (
+	OrientationAny       = "any"
+	OrientationLandscape = "landscape"
+	OrientationPortrait  = "portrait"
+	OrientationSquare    = "square"
+)
+
+// Filter selects images by size and shape. Zero limits are not checked.
+type Filter struct {
+	MinWidth, MinHeight int
+	MaxWidth, MaxHeight int
+	Orientation         string // OrientationAny when empty
+}
+
+func (f Filter) active() bool {
+	return f != Filter{} && f != Filter{Orientation: OrientationAny}
+}
+
+func (f Filter) validate() error {
+	switch f.Orientation {
+	case "", OrientationAny, OrientationLandscape, OrientationPortrait, OrientationSquare:
+	default:
+		return fmt.Errorf("invalid orientation %q (use any, landscape, portrait or square)", f.Orientation)
+	}
+	if f.MaxWidth > 0 && f.MaxWidth < f.MinWidth || f.MaxHeight > 0 && f.MaxHeight < f.MinHeight {
+		return fmt.Errorf("maximum size is below the minimum")
+	}
+	return nil
+}
+
+// accepts reports whether an image of size passes the filter.
+func (f Filter) accepts(size image.Point) bool {
+	if size.X < f.MinWidth || size.Y < f.MinHeight ||
+		f.MaxWidth > 0 && size.X > f.MaxWidth || f.MaxHeight > 0 && size.Y > f.MaxHeight {
+		return false
+	}
+	switch f.Orientation {
+	case OrientationLandscape:
+		return size.X > size.Y
+	case OrientationPortrait:
+		return size.Y > size.X
+	case OrientationSquare:
+		return size.X == size.Y
+	}
+	return true
+}
+
+// FilterImages keeps the paths whose image headers pass f. Unreadable files
+// are kept for the assembler to report.
+func FilterImages(paths []string, f Filter) ([]string, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	if !f.active() {
+		return paths, nil
+	}
+	var kept []string
+	for _, p := range paths {
+		size, err := exif.ImageSize(p)
+		if err == nil && !f.accepts(size) {
+			logger.Debugf("Filtered out %s (%dx%d)", p, size.X, size.Y)
+			continue
+		}
+		kept = append(kept, p)
+	}
+	if skipped := len(paths) - len(kept); skipped > 0 {
+		logger.Infof("Filtered out %d of %d images by size or orientation", skipped, len(paths))
+	}
+	return kept, nil
+}
+
+// ListDirectory returns the image files in dir, or below it when recursive,
+// in lexical order. Extensions are matched case-insensitively. Paths
+// relative to dir that match exclude, in the wildcard syntax of package
+// match, are skipped along with excluded directories.
+func ListDirectory(dir string, recursive bool, exclude []string) ([]string, error) {
+	excl := match.New(exclude)
+	var paths []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir {
+				return err
+			}
+			logger.WithError(err).Warn("Skipping unreadable path: ", p)
+			return nil
+		}
+		if p == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if !recursive || excl.Match(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if imageExts[strings.ToLower(filepath.Ext(p))] && !excl.Match(rel) {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	return paths, err
+}
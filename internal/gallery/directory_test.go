@@ -0,0 +1,110 @@
+package gallery
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writePNG(t *testing.T, path string, w, h int) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func relTo(t *testing.T, root string, paths []string) []string {
+	t.Helper()
+	var rel []string
+	for _, p := range paths {
+		r, err := filepath.Rel(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	return rel
+}
+
+func TestListDirectory(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"A.JPG", "b.jpeg", "c.Png", "notes.txt", "trip/d.webp", "trip/raw/e.jpg", "trip/f_edit.jpg"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ListDirectory(root, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A.JPG", "b.jpeg", "c.Png"}; !reflect.DeepEqual(relTo(t, root, got), want) {
+		t.Errorf("flat = %q, want %q", relTo(t, root, got), want)
+	}
+
+	got, err = ListDirectory(root, true, []string{"raw/...", "*_edit.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A.JPG", "b.jpeg", "c.Png", "trip/d.webp"}; !reflect.DeepEqual(relTo(t, root, got), want) {
+		t.Errorf("recursive = %q, want %q", relTo(t, root, got), want)
+	}
+
+	got, _ = ListDirectory(root, true, []string{"./trip/...", "[Ab].*", "!b.jpeg"})
+	if want := []string{"b.jpeg", "c.Png"}; !reflect.DeepEqual(relTo(t, root, got), want) {
+		t.Errorf("anchored = %q, want %q", relTo(t, root, got), want)
+	}
+
+	if _, err := ListDirectory(filepath.Join(root, "missing"), true, nil); err == nil {
+		t.Error("missing directory gave no error")
+	}
+}
+
+func TestFilterImages(t *testing.T) {
+	dir := t.TempDir()
+	wide := writePNG(t, filepath.Join(dir, "wide.png"), 300, 200)
+	tall := writePNG(t, filepath.Join(dir, "tall.png"), 200, 300)
+	small := writePNG(t, filepath.Join(dir, "small.png"), 50, 50)
+	paths := []string{wide, tall, small}
+
+	tests := []struct {
+		filter Filter
+		want   []string
+	}{
+		{Filter{}, paths},
+		{Filter{Orientation: OrientationAny}, paths},
+		{Filter{MinWidth: 100}, []string{wide, tall}},
+		{Filter{MaxHeight: 250}, []string{wide, small}},
+		{Filter{Orientation: OrientationPortrait}, []string{tall}},
+		{Filter{Orientation: OrientationSquare}, []string{small}},
+		{Filter{MinHeight: 100, Orientation: OrientationLandscape}, []string{wide}},
+	}
+	for _, tt := range tests {
+		got, err := FilterImages(paths, tt.filter)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterImages(%+v) = %q, %v; want %q", tt.filter, relTo(t, dir, got), err, relTo(t, dir, tt.want))
+		}
+	}
+
+	if _, err := FilterImages(paths, Filter{Orientation: "diagonal"}); err == nil {
+		t.Error("unknown orientation accepted")
+	}
+	if _, err := FilterImages(paths, Filter{MinWidth: 500, MaxWidth: 100}); err == nil {
+		t.Error("max below min accepted")
+	}
+}
@@ -2,109 +2,217 @@ package gallery
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"handytools/pkg/common"
-	"io"
-	"net/http"
-	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/chromedp/chromedp"
 )
 
-func DownloadPins(boardURL, outputDir string) ([]string, error) {
-	logger := common.GetLogger()
-	ctx, cancel := chromedp.NewContext(context.Background())
+// DownloadPins scrapes the images of a Pinterest board and fetches them, at
+// the best available resolution, through d.
+func DownloadPins(boardURL string, opts PinterestOptions, d *Downloader) ([]string, error) {
+	return Download(context.Background(), pinterestSource{url: boardURL, opts: opts}, d)
+}
+
+// Download lists the images of src, fetches them through d and writes a
+// manifest of where each came from to the cache directory. It returns the
+// cached files in source order.
+func Download(ctx context.Context, src Source, d *Downloader) ([]string, error) {
+	logger.Infof("Reading %s", src.Location())
+	images, err := src.Images(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Found %d unique image links", len(images))
+
+	jobs := make([][]string, len(images))
+	for i, img := range images {
+		jobs[i] = candidateURLs(img)
+	}
+	var imagePaths []string
+	m := manifest{Source: src.Location()}
+	for i, r := range d.FetchAll(ctx, jobs) {
+		if r.Err != nil {
+			logger.WithError(r.Err).Warnf("Failed to download: %s", images[i].Src)
+			continue
+		}
+		imagePaths = append(imagePaths, r.Path)
+		m.Pins = append(m.Pins, manifestEntry{File: filepath.Base(r.Path), Thumbnail: images[i].Src, URL: r.URL, pinMeta: images[i].pinMeta})
+		logger.Infof("Saved: %s", r.Path)
+	}
+
+	path := manifestPath(d.Dir, src.Location())
+	if err := writeManifest(path, m); err != nil {
+		logger.WithError(err).Warn("Failed to write manifest")
+	} else {
+		logger.Infof("Manifest saved: %s", path)
+	}
+	return imagePaths, nil
+}
+
+// Scroll limits for scrapeBoard.
+const (
+	scrollPatience = 5                      // Idle steps before the board counts as fully loaded
+	maxScrollSteps = 1000                   // Safety limit for boards that never settle
+	scrollWait     = 500 * time.Millisecond // Time for new pins to load after each scroll
+)
+
+// boardImagesJS lists the pin images of the board grid with the pin ID,
+// title, alt text as description, the outbound link and placeholder colour,
+// leaving out the "more ideas" modules Pinterest mixes in. Grid items do not
+// say which section a pin is in, so only a section page's own heading is
+// recorded as the section. It also returns the page height, the pin count
+// shown in the board header and the number of section tiles on the board.
+const boardImagesJS = `(() => {
+	const parts = location.pathname.split('/').filter(p => p);
+	const heading = document.querySelector('h1');
+	const section = parts.length === 3 && heading ? heading.innerText.trim() : "";
+	const images = Array.from(document.querySelectorAll('div[data-grid-item="true"] img')).filter(img => {
+		let parent = img.closest('div[data-test-id="related-interests-multi-column-module"]');
+		let boardParent = img.closest('div[data-test-id="board-feed"]');
+		return !parent && boardParent;
+	}).filter(img => img.src.includes("pinimg.com")).map(img => {
+		const item = img.closest('div[data-grid-item="true"]');
+		const pin = item.querySelector('a[href*="/pin/"]');
+		const id = pin ? (pin.getAttribute('href').match(/\/pin\/([^\/?]+)/) || [])[1] || "" : "";
+		const outbound = Array.from(item.querySelectorAll('a[href^="http"]')).find(a => !/pinterest\.|pinimg\.com/.test(a.hostname));
+		const title = item.querySelector('[data-test-id="pinrep-footer"] [title], [data-test-id="pin-title"]');
+		const tinted = img.closest('[style*="background"]');
+		return {
+			src: img.src, srcset: img.srcset || "", id: id,
+			title: title ? (title.getAttribute('title') || title.innerText).trim() : img.alt || "",
+			description: img.alt || "",
+			link: outbound ? outbound.href : "",
+			section: section,
+			color: tinted ? tinted.style.backgroundColor : "",
+		};
+	});
+	const count = (document.body.innerText.match(/([\d,.]+)\s+Pins\b/) || [])[1] || "0";
+	return {
+		images: images,
+		height: document.documentElement.scrollHeight,
+		total: parseInt(count.replace(/[,.]/g, ""), 10) || 0,
+		sections: section ? 0 : document.querySelectorAll('div[data-test-id="board-section"]').length,
+	};
+})()`
+
+// boardSnapshot is one evaluation of boardImagesJS.
+type boardSnapshot struct {
+	Images   []remoteImage `json:"images"`
+	Height   float64       `json:"height"`
+	Total    int           `json:"total"`
+	Sections int           `json:"sections"`
+}
+
+// scrollState collects pins while a board is scrolled and decides when to
+// stop: once several steps in a row found no new pins and the page stopped
+// growing. Before the first pin it waits three times as long for the board
+// to load.
+type scrollState struct {
+	pins     []remoteImage
+	seen     map[string]bool
+	height   float64
+	idle     int
+	sections int // Section tiles seen on the board
+}
+
+// step records a snapshot and reports whether to keep scrolling.
+func (s *scrollState) step(snap boardSnapshot) bool {
+	if s.seen == nil {
+		s.seen = map[string]bool{}
+	}
+	grew := snap.Height != s.height
+	s.height = snap.Height
+	s.sections = max(s.sections, snap.Sections)
+	added := false
+	for _, img := range snap.Images {
+		if !s.seen[img.Src] {
+			s.seen[img.Src] = true
+			img.Color = hexColor(img.Color)
+			if img.Description == img.Title {
+				img.Description = ""
+			}
+			s.pins = append(s.pins, img)
+			added = true
+		}
+	}
+	if added || grew {
+		s.idle = 0
+		return true
+	}
+	s.idle++
+	if len(s.pins) == 0 {
+		return s.idle < 3*scrollPatience
+	}
+	return s.idle < scrollPatience
+}
+
+// missingSections reports whether the board has sections that the scraped
+// pins do not record.
+func (s *scrollState) missingSections() bool {
+	if s.sections == 0 {
+		return false
+	}
+	for _, pin := range s.pins {
+		if pin.Section != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// scrapeBoard scrolls through a Pinterest board in headless Chrome and
+// collects the images of its pins, giving up after timeout.
+func scrapeBoard(ctx context.Context, boardURL string, timeout time.Duration) ([]remoteImage, error) {
+	ctx, cancel := chromedp.NewContext(ctx)
 	defer cancel()
-	ctx, cancel = context.WithTimeout(ctx, 120*time.Second)
+	ctx, cancel = context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	var imageLinks []string
+	var state scrollState
+	bar := newProgress("Pins")
+	defer bar.done()
 
 	logger.Info("Opening board in headless Chrome...")
 
 	err := chromedp.Run(ctx,
 		chromedp.Navigate(boardURL),
-		chromedp.Sleep(3*time.Second),
+		chromedp.WaitReady("body"),
 
 		chromedp.ActionFunc(func(ctx context.Context) error {
-			var allLinks []string
-			for i := 0; i < 40; i++ {
-				logger.Infof("Scrolling step %d/40", i+1)
-				var newLinks []string
-				err := chromedp.Run(ctx,
-					chromedp.Evaluate(`
-						Array.from(document.querySelectorAll('div[data-grid-item="true"] img')).filter(img => {
-							let parent = img.closest('div[data-test-id="related-interests-multi-column-module"]');
-							let boardParent = img.closest('div[data-test-id="board-feed"]');
-							return !parent && boardParent;
-						}).map(img => img.src).filter(src => src.includes("pinimg.com"));
-					`, &newLinks),
-				)
-				if err != nil {
+			for i := 0; i < maxScrollSteps; i++ {
+				var snap boardSnapshot
+				if err := chromedp.Evaluate(boardImagesJS, &snap).Do(ctx); err != nil {
 					return err
 				}
-				allLinks = append(allLinks, newLinks...)
-				if len(allLinks) > 0 && len(newLinks) == 0 {
-					logger.Infof("No new images, break")
-					break
+				more := state.step(snap)
+				bar.update(len(state.pins), snap.Total)
+				logger.Debugf("Scrolling step %d: %d pins, page height %.0f", i+1, len(state.pins), snap.Height)
+				if !more {
+					logger.Infof("No new pins after %d steps, stopping", scrollPatience)
+					return nil
 				}
-
 				if err := chromedp.Run(ctx,
-					chromedp.Evaluate(`window.scrollBy(0, 500)`, nil),
-					chromedp.Sleep(500*time.Millisecond),
+					chromedp.Evaluate(`window.scrollBy(0, window.innerHeight)`, nil),
+					chromedp.Sleep(scrollWait),
 				); err != nil {
 					return err
 				}
 			}
-			// remove duplicates
-			seen := map[string]bool{}
-			imageLinks = nil
-			for _, link := range allLinks {
-				if !seen[link] {
-					seen[link] = true
-					imageLinks = append(imageLinks, link)
-				}
-			}
 			return nil
 		}),
 	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch pins: %w", err)
+	if state.missingSections() {
+		logger.Warnf("The board has %d sections that headless Chrome cannot assign pins to; use --scraper http to record them for --manifest", state.sections)
 	}
-
-	logger.Infof("Found %d unique image links", len(imageLinks))
-
-	var imagePaths []string
-	i := 1
-	for _, link := range imageLinks {
-		path := filepath.Join(outputDir, fmt.Sprintf("pin_%03d.jpg", i))
-		if err := downloadImage(link, path); err != nil {
-			logger.WithError(err).Warnf("Failed to download: %s", link)
-			continue
-		}
-		imagePaths = append(imagePaths, path)
-		logger.Infof("Saved: %s", path)
-		i++
+	if errors.Is(err, context.DeadlineExceeded) && len(state.pins) > 0 {
+		logger.Warnf("Timed out after %s, keeping the %d pins found so far", timeout, len(state.pins))
+		return state.pins, nil
 	}
-
-	return imagePaths, nil
-}
-
-func downloadImage(url, path string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	out, err := os.Create(path)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to fetch pins: %w", err)
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return err
+	return state.pins, nil
 }
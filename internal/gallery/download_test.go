@@ -0,0 +1,85 @@
+package gallery
+
+import "testing"
+
+func snapshot(height float64, srcs ...string) boardSnapshot {
+	snap := boardSnapshot{Height: height}
+	for _, src := range srcs {
+		snap.Images = append(snap.Images, remoteImage{Src: src})
+	}
+	return snap
+}
+
+func TestScrollState_StopsOnStagnation(t *testing.T) {
+	var s scrollState
+	if !s.step(snapshot(1000, "a", "b")) {
+		t.Fatal("stopped after the first pins")
+	}
+	// The grid is virtualised: earlier pins leave the DOM as new ones load.
+	if !s.step(snapshot(1800, "b", "c")) {
+		t.Fatal("stopped while new pins were loading")
+	}
+	// No new pins, but the page grew: more are on their way.
+	if !s.step(snapshot(2400, "b", "c")) {
+		t.Fatal("stopped while the page was growing")
+	}
+	steps := 0
+	for s.step(snapshot(2400, "c")) {
+		steps++
+		if steps > scrollPatience {
+			t.Fatal("kept scrolling a settled board")
+		}
+	}
+	if steps != scrollPatience-1 {
+		t.Errorf("stopped after %d idle steps, want %d", steps+1, scrollPatience)
+	}
+	if len(s.pins) != 3 || s.pins[2].Src != "c" {
+		t.Errorf("pins = %+v, want a, b, c", s.pins)
+	}
+}
+
+func TestScrollState_WaitsLongerForFirstPins(t *testing.T) {
+	var s scrollState
+	s.step(snapshot(800))
+	idle := 1
+	for s.step(snapshot(800)) {
+		idle++
+	}
+	if idle != 3*scrollPatience {
+		t.Errorf("gave up on an empty board after %d idle steps, want %d", idle, 3*scrollPatience)
+	}
+}
+
+func TestScrollState_KeepsPinMetadata(t *testing.T) {
+	var s scrollState
+	snap := boardSnapshot{Height: 900, Images: []remoteImage{
+		{Src: "a", pinMeta: pinMeta{ID: "1", Title: "Lamp", Description: "A brass lamp", Link: "https://shop.example/lamp", Color: "rgb(255, 0, 16)"}},
+		{Src: "b", pinMeta: pinMeta{ID: "2", Title: "Chair", Description: "Chair"}},
+	}}
+	s.step(snap)
+	a, b := s.pins[0], s.pins[1]
+	if a.Description != "A brass lamp" || a.Link != "https://shop.example/lamp" || a.Color != "#ff0010" {
+		t.Errorf("first pin = %+v", a.pinMeta)
+	}
+	if b.Description != "" {
+		t.Errorf("description repeating the title was kept: %q", b.Description)
+	}
+}
+
+func TestScrollState_MissingSections(t *testing.T) {
+	var s scrollState
+	s.step(snapshot(900, "a"))
+	if s.missingSections() {
+		t.Error("board without sections reported as missing them")
+	}
+	snap := snapshot(1200, "b")
+	snap.Sections = 2
+	s.step(snap)
+	if !s.missingSections() {
+		t.Error("expected sections to be reported as missing")
+	}
+	s.pins[0].Section = "Kitchen"
+	if s.missingSections() {
+		t.Error("section page reported as missing sections")
+	}
+}
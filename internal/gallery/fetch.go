@@ -0,0 +1,283 @@
+package gallery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Downloader fetches images into a content-addressed cache directory: each
+// URL is stored once under a name derived from its hash, so repeated runs
+// reuse earlier downloads. Requests run in parallel, are retried with
+// exponential backoff and are spaced out per host.
+type Downloader struct {
+	Client  *http.Client
+	Dir     string        // Cache directory
+	Workers int           // Parallel downloads
+	Retries int           // Extra attempts after a network error, 429 or 5xx
+	Backoff time.Duration // Wait before the first retry, doubled for each further one
+	HostGap time.Duration // Minimum time between requests to the same host
+
+	mu   sync.Mutex
+	next map[string]time.Time // Earliest time of the next request per host
+}
+
+// NewDownloader returns a Downloader with the default limits caching in dir.
+func NewDownloader(dir string) *Downloader {
+	return &Downloader{
+		Client:  &http.Client{Timeout: 30 * time.Second},
+		Dir:     dir,
+		Workers: 4,
+		Retries: 3,
+		Backoff: 500 * time.Millisecond,
+		HostGap: 100 * time.Millisecond,
+	}
+}
+
+// DefaultCacheDir is the user cache directory for downloaded images, or a
+// directory under the system temp directory when there is none.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "handytools", "images")
+}
+
+// statusError is a response other than 200 OK.
+type statusError struct {
+	url    string
+	status string
+	code   int
+}
+
+func (e *statusError) Error() string { return e.url + ": " + e.status }
+
+// retryable reports whether err may go away on another attempt.
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	var invalid *invalidImageError
+	return !errors.As(err, &invalid) && !errors.Is(err, context.Canceled) && !errors.Is(err, fs.ErrNotExist)
+}
+
+// invalidImageError is a response body that does not decode as an image,
+// such as an HTML error page served with 200 OK.
+type invalidImageError struct {
+	url string
+	err error
+}
+
+func (e *invalidImageError) Error() string {
+	return fmt.Sprintf("%s: not an image: %v", e.url, e.err)
+}
+
+// Fetch returns the cached file for rawURL, downloading it first if needed.
+// File URLs are copied again on every call, as the file may have changed.
+func (d *Downloader) Fetch(ctx context.Context, rawURL string) (string, error) {
+	key := cacheKey(rawURL)
+	if path, ok := d.cached(key); ok && !strings.HasPrefix(rawURL, "file:") {
+		return path, nil
+	}
+	var err error
+	for attempt := 0; attempt <= d.Retries; attempt++ {
+		if attempt > 0 {
+			wait := d.Backoff << (attempt - 1)
+			logger.WithError(err).Debugf("Retrying %s in %s", rawURL, wait)
+			if err := sleep(ctx, wait); err != nil {
+				return "", err
+			}
+		}
+		var path string
+		if path, err = d.download(ctx, rawURL, key); err == nil {
+			return path, nil
+		}
+		if !retryable(err) {
+			break
+		}
+	}
+	return "", err
+}
+
+// FetchFirst tries urls in order and returns the cached file of the first
+// one that downloads, along with that URL.
+func (d *Downloader) FetchFirst(ctx context.Context, urls []string) (string, string, error) {
+	err := errors.New("no URL to download")
+	for _, u := range urls {
+		var path string
+		if path, err = d.Fetch(ctx, u); err == nil {
+			return path, u, nil
+		}
+		logger.WithError(err).Debugf("Falling back from %s", u)
+	}
+	return "", "", err
+}
+
+// fetchResult is the outcome of one FetchAll job.
+type fetchResult struct {
+	Path string // Cached file
+	URL  string // Candidate that was downloaded
+	Err  error
+}
+
+// FetchAll runs FetchFirst for every list of candidates on Workers
+// goroutines. Results are in the order of jobs.
+func (d *Downloader) FetchAll(ctx context.Context, jobs [][]string) []fetchResult {
+	results := make([]fetchResult, len(jobs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(d.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				r := &results[i]
+				r.Path, r.URL, r.Err = d.FetchFirst(ctx, jobs[i])
+			}
+		}()
+	}
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// download performs one GET of rawURL, or reads it for a file URL, and moves
+// the body into the cache once it has been validated as an image.
+func (d *Downloader) download(ctx context.Context, rawURL, key string) (string, error) {
+	body, err := d.open(ctx, rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(d.Dir, key+"-*.part")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	_, format, err := image.DecodeConfig(tmp)
+	tmp.Close()
+	if err != nil {
+		return "", &invalidImageError{url: rawURL, err: err}
+	}
+
+	path := filepath.Join(d.Dir, key+formatExt(format))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// open returns the body of rawURL: the local file of a file URL, or else the
+// response to a GET, sent once the host's HostGap has passed.
+func (d *Downloader) open(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "file" {
+		return os.Open(filepath.FromSlash(u.Path))
+	}
+	if err := d.waitHost(ctx, u.Host); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &statusError{url: rawURL, status: resp.Status, code: resp.StatusCode}
+	}
+	return resp.Body, nil
+}
+
+// cached finds a file stored for key.
+func (d *Downloader) cached(key string) (string, bool) {
+	matches, _ := filepath.Glob(filepath.Join(d.Dir, key+".*"))
+	if len(matches) == 0 {
+		return "", false
+	}
+	return matches[0], true
+}
+
+// waitHost blocks until a request to host is allowed by HostGap.
+func (d *Downloader) waitHost(ctx context.Context, host string) error {
+	d.mu.Lock()
+	if d.next == nil {
+		d.next = map[string]time.Time{}
+	}
+	now := time.Now()
+	at := d.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	d.next[host] = at.Add(d.HostGap)
+	d.mu.Unlock()
+	return sleep(ctx, time.Until(at))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// cacheKey names the cache entry of a URL.
+func cacheKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:16])
+}
+
+// formatExt is the file extension for an image.DecodeConfig format name.
+func formatExt(format string) string {
+	if format == "jpeg" {
+		return ".jpg"
+	}
+	return "." + format
+}
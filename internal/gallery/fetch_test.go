@@ -0,0 +1,179 @@
+package gallery
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// imageServer serves a PNG at /ok, an HTML page at /html, 404 at /missing and
+// a PNG at /flaky after two 503s. It counts requests per path.
+func imageServer(t *testing.T) (*httptest.Server, map[string]*int32) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]*int32{"/ok": new(int32), "/html": new(int32), "/missing": new(int32), "/flaky": new(int32)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, ok := counts[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		count := atomic.AddInt32(n, 1)
+		switch r.URL.Path {
+		case "/ok":
+			w.Write(buf.Bytes())
+		case "/html":
+			w.Write([]byte("<html><body>Forbidden</body></html>"))
+		case "/missing":
+			http.NotFound(w, r)
+		case "/flaky":
+			if count <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write(buf.Bytes())
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, counts
+}
+
+func testDownloader(t *testing.T) *Downloader {
+	d := NewDownloader(t.TempDir())
+	d.Backoff = time.Millisecond
+	d.HostGap = 0
+	return d
+}
+
+func TestDownloaderFetch_CachesByURL(t *testing.T) {
+	srv, counts := imageServer(t)
+	d := testDownloader(t)
+
+	path, err := d.Fetch(context.Background(), srv.URL+"/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Ext(path) != ".png" || filepath.Dir(path) != d.Dir {
+		t.Errorf("cached at %s, want a .png in %s", path, d.Dir)
+	}
+	again, err := d.Fetch(context.Background(), srv.URL+"/ok")
+	if err != nil || again != path {
+		t.Errorf("second fetch = %s, %v; want %s", again, err, path)
+	}
+	if n := *counts["/ok"]; n != 1 {
+		t.Errorf("server saw %d requests, want 1", n)
+	}
+}
+
+func TestDownloaderFetch_Errors(t *testing.T) {
+	srv, counts := imageServer(t)
+	d := testDownloader(t)
+
+	if _, err := d.Fetch(context.Background(), srv.URL+"/html"); err == nil {
+		t.Error("HTML page was accepted as an image")
+	}
+	if _, err := d.Fetch(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("404 was accepted")
+	}
+	if *counts["/html"] != 1 || *counts["/missing"] != 1 {
+		t.Errorf("permanent failures were retried: html %d, missing %d", *counts["/html"], *counts["/missing"])
+	}
+	if _, ok := d.cached(cacheKey(srv.URL + "/html")); ok {
+		t.Error("invalid body was left in the cache")
+	}
+
+	if _, err := d.Fetch(context.Background(), srv.URL+"/flaky"); err != nil {
+		t.Errorf("flaky fetch failed after retries: %v", err)
+	}
+	if n := *counts["/flaky"]; n != 3 {
+		t.Errorf("flaky server saw %d requests, want 3", n)
+	}
+}
+
+func TestDownloaderFetch_CopiesLocalFiles(t *testing.T) {
+	d := testDownloader(t)
+	src := filepath.Join(t.TempDir(), "local.png")
+	if err := os.WriteFile(src, mustPNG(t, 4, 3), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path, err := d.Fetch(context.Background(), fileURL(src).String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != d.Dir || filepath.Ext(path) != ".png" {
+		t.Errorf("copied to %s, want a .png in %s", path, d.Dir)
+	}
+	// A changed file is copied again rather than served from the cache.
+	if err := os.WriteFile(src, mustPNG(t, 8, 8), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Fetch(context.Background(), fileURL(src).String()); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, mustPNG(t, 8, 8)) {
+		t.Errorf("cached copy was not updated: %v", err)
+	}
+	if _, err := d.Fetch(context.Background(), fileURL(filepath.Join(d.Dir, "gone.png")).String()); err == nil {
+		t.Error("missing file was accepted")
+	}
+}
+
+func mustPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownloaderFetchAll_FallsBackInOrder(t *testing.T) {
+	srv, _ := imageServer(t)
+	d := testDownloader(t)
+
+	jobs := [][]string{
+		{srv.URL + "/missing", srv.URL + "/ok"},
+		{srv.URL + "/html"},
+		{srv.URL + "/ok"},
+	}
+	results := d.FetchAll(context.Background(), jobs)
+	if results[0].Err != nil || results[0].URL != srv.URL+"/ok" {
+		t.Errorf("job 0 = %+v, want fallback to /ok", results[0])
+	}
+	if results[1].Err == nil {
+		t.Errorf("job 1 = %+v, want an error", results[1])
+	}
+	if results[2].Err != nil || results[2].Path != results[0].Path {
+		t.Errorf("job 2 = %+v, want the cached file %s", results[2], results[0].Path)
+	}
+}
+
+func TestDownloaderWaitHost_SpacesRequests(t *testing.T) {
+	d := testDownloader(t)
+	d.HostGap = 20 * time.Millisecond
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := d.waitHost(context.Background(), "example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("three requests took %s, want at least 40ms", elapsed)
+	}
+	start = time.Now()
+	d.waitHost(context.Background(), "other.example.com")
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("another host waited %s", elapsed)
+	}
+}
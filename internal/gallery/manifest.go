@@ -0,0 +1,133 @@
+package gallery
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pinMeta describes a pin beyond its image. Sources other than Pinterest
+// leave it empty.
+type pinMeta struct {
+	ID          string `json:"id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Link        string `json:"link,omitempty"`    // Page the pin was saved from
+	Color       string `json:"color,omitempty"`   // Dominant colour, #rrggbb
+	Section     string `json:"section,omitempty"` // Board section
+}
+
+// manifest records where the downloaded images of a source came from, in
+// source order. img gallery --manifest reads it back as input.
+type manifest struct {
+	Source string          `json:"source"`
+	Pins   []manifestEntry `json:"pins"`
+}
+
+type manifestEntry struct {
+	File      string `json:"file"`      // Path relative to the manifest
+	Thumbnail string `json:"thumbnail"` // URL shown on the board
+	URL       string `json:"url"`       // URL actually downloaded, the original when available
+	pinMeta
+	Removed string `json:"removed,omitempty"` // Date a sync found the pin gone from the board
+}
+
+// manifestPath is the manifest of a source in the cache directory, next to
+// its images.
+func manifestPath(dir, location string) string {
+	return filepath.Join(dir, "board_"+cacheKey(location)[:12]+".json")
+}
+
+func writeManifest(path string, m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func readManifest(path string) (manifest, error) {
+	var m manifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ManifestInput is a manifest read back as gallery input.
+type ManifestInput struct {
+	Paths    []string          // Images in source order
+	Captions map[string]string // Pin title, else description, by path
+	Sections map[string]string // Board section by path, empty when the board has none
+}
+
+// LoadManifest reads a manifest written by a download or sync. Pins marked
+// removed are left out and images missing from disk are skipped with a
+// warning.
+func LoadManifest(path string) (ManifestInput, error) {
+	in := ManifestInput{Captions: map[string]string{}, Sections: map[string]string{}}
+	m, err := readManifest(path)
+	if err != nil {
+		return in, err
+	}
+	dir := filepath.Dir(path)
+	for _, pin := range m.Pins {
+		if pin.Removed != "" {
+			continue
+		}
+		file := pin.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if _, err := os.Stat(file); err != nil {
+			logger.Warnf("Skipping missing image %s", file)
+			continue
+		}
+		in.Paths = append(in.Paths, file)
+		if caption := strings.TrimSpace(pin.Title); caption != "" {
+			in.Captions[file] = caption
+		} else if caption := strings.TrimSpace(pin.Description); caption != "" {
+			in.Captions[file] = caption
+		}
+		if pin.Section != "" {
+			in.Sections[file] = pin.Section
+		}
+	}
+	if len(in.Sections) == 0 {
+		in.Sections = nil
+	}
+	return in, nil
+}
+
+var cssRGB = regexp.MustCompile(`^rgba?\(\s*(\d+)\s*,\s*(\d+)\s*,\s*(\d+)`)
+
+// hexColor normalises a CSS colour as returned by the browser, rgb(r, g, b),
+// to #rrggbb. Hex colours are lower-cased and anything else is dropped.
+func hexColor(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if m := cssRGB.FindStringSubmatch(s); m != nil {
+		var rgb [3]int
+		for i := range rgb {
+			rgb[i], _ = strconv.Atoi(m[i+1])
+			rgb[i] = min(rgb[i], 255)
+		}
+		return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+	}
+	if len(s) == 7 && strings.HasPrefix(s, "#") {
+		if _, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package gallery
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "board.json")
+	err := writeManifest(path, manifest{Source: "https://www.pinterest.com/anna/moods/", Pins: []manifestEntry{
+		{File: "a.jpg", pinMeta: pinMeta{ID: "1", Title: "Kitchen", Section: "Rooms"}},
+		{File: "gone.jpg", pinMeta: pinMeta{ID: "2"}},
+		{File: "b.jpg", pinMeta: pinMeta{ID: "3", Description: "Only a description"}},
+		{File: "c.jpg", pinMeta: pinMeta{ID: "4", Section: "Garden"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := filepath.Join(dir, "a.jpg"), filepath.Join(dir, "b.jpg"), filepath.Join(dir, "c.jpg")
+	if want := []string{a, b, c}; !reflect.DeepEqual(in.Paths, want) {
+		t.Errorf("paths = %q, want %q", in.Paths, want)
+	}
+	if want := map[string]string{a: "Kitchen", b: "Only a description"}; !reflect.DeepEqual(in.Captions, want) {
+		t.Errorf("captions = %q, want %q", in.Captions, want)
+	}
+	if want := map[string]string{a: "Rooms", c: "Garden"}; !reflect.DeepEqual(in.Sections, want) {
+		t.Errorf("sections = %q, want %q", in.Sections, want)
+	}
+}
+
+func TestHexColor(t *testing.T) {
+	tests := map[string]string{
+		"rgb(200, 180, 154)": "#c8b49a",
+		"rgba(0, 0, 0, 0.5)": "#000000",
+		"#C8B49A":            "#c8b49a",
+		"":                   "",
+		"transparent":        "",
+		"#fff":               "",
+	}
+	for in, want := range tests {
+		if got := hexColor(in); got != want {
+			t.Errorf("hexColor(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package gallery
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// remoteImage is an image found by a Source, with its srcset when it has one
+// and, for pins, what else is known about the pin.
+type remoteImage struct {
+	Src    string `json:"src"`
+	Srcset string `json:"srcset"`
+	pinMeta
+}
+
+// pinimgSize matches the size segment of pinimg.com paths such as
+// /236x/, /474x/, /736x/ or /170x170/.
+var pinimgSize = regexp.MustCompile(`^/(\d+x\d*|originals)/`)
+
+// originalURL rewrites a pinimg.com thumbnail URL to its originals/ variant.
+func originalURL(src string) (string, bool) {
+	u, err := url.Parse(src)
+	if err != nil || !strings.HasSuffix(u.Hostname(), "pinimg.com") {
+		return "", false
+	}
+	m := pinimgSize.FindStringSubmatch(u.Path)
+	if m == nil || m[1] == "originals" {
+		return "", false
+	}
+	u.Path = "/originals/" + strings.TrimPrefix(u.Path, m[0])
+	return u.String(), true
+}
+
+// largestSrcset returns the candidate of a srcset attribute with the highest
+// width (w) or density (x) descriptor.
+func largestSrcset(srcset string) string {
+	var best string
+	bestSize := -1.0
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		size := 1.0
+		if len(fields) > 1 {
+			d := fields[1]
+			if v, err := strconv.ParseFloat(d[:len(d)-1], 64); err == nil && (strings.HasSuffix(d, "w") || strings.HasSuffix(d, "x")) {
+				size = v
+			}
+		}
+		if size > bestSize {
+			best, bestSize = fields[0], size
+		}
+	}
+	return best
+}
+
+// candidateURLs lists the URLs to try for a pin, best first: the original,
+// the largest srcset entry and finally the thumbnail itself.
+func candidateURLs(img remoteImage) []string {
+	var urls []string
+	seen := map[string]bool{}
+	add := func(u string) {
+		if u != "" && !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	if u, ok := originalURL(img.Src); ok {
+		add(u)
+	}
+	add(largestSrcset(img.Srcset))
+	add(img.Src)
+	return urls
+}
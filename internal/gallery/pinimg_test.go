@@ -0,0 +1,55 @@
+package gallery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOriginalURL(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+		ok   bool
+	}{
+		{"https://i.pinimg.com/236x/ab/cd/ef/abcdef.jpg", "https://i.pinimg.com/originals/ab/cd/ef/abcdef.jpg", true},
+		{"https://i.pinimg.com/170x170/ab/cd/abcd.jpg", "https://i.pinimg.com/originals/ab/cd/abcd.jpg", true},
+		{"https://i.pinimg.com/originals/ab/cd/abcd.jpg", "", false},
+		{"https://example.com/236x/ab.jpg", "", false},
+		{"https://i.pinimg.com/videos/thumbnails/ab.jpg", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := originalURL(tt.src)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("originalURL(%q) = %q, %v; want %q, %v", tt.src, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLargestSrcset(t *testing.T) {
+	tests := map[string]string{
+		"https://i.pinimg.com/236x/a.jpg 1x, https://i.pinimg.com/474x/a.jpg 2x, https://i.pinimg.com/736x/a.jpg 3x": "https://i.pinimg.com/736x/a.jpg",
+		"a.jpg 736w, b.jpg 236w": "a.jpg",
+		"a.jpg":                  "a.jpg",
+		"":                       "",
+	}
+	for srcset, want := range tests {
+		if got := largestSrcset(srcset); got != want {
+			t.Errorf("largestSrcset(%q) = %q, want %q", srcset, got, want)
+		}
+	}
+}
+
+func TestCandidateURLs(t *testing.T) {
+	got := candidateURLs(remoteImage{
+		Src:    "https://i.pinimg.com/236x/a/b.jpg",
+		Srcset: "https://i.pinimg.com/236x/a/b.jpg 1x, https://i.pinimg.com/736x/a/b.jpg 3x",
+	})
+	want := []string{
+		"https://i.pinimg.com/originals/a/b.jpg",
+		"https://i.pinimg.com/736x/a/b.jpg",
+		"https://i.pinimg.com/236x/a/b.jpg",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("candidateURLs = %q, want %q", got, want)
+	}
+}
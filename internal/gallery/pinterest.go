@@ -0,0 +1,307 @@
+package gallery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Pinterest scrapers accepted by pinterestSource.
+const (
+	ScraperAuto    = "auto"    // Plain HTTP, then headless Chrome if that finds nothing
+	ScraperHTTP    = "http"    // Embedded page state and the resource API only
+	ScraperBrowser = "browser" // Headless Chrome only
+)
+
+// ParseScraper checks a --scraper value, treating an empty one as ScraperAuto.
+func ParseScraper(s string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(s)); v {
+	case "":
+		return ScraperAuto, nil
+	case ScraperAuto, ScraperHTTP, ScraperBrowser:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid scraper %q (use auto, http or browser)", s)
+}
+
+// maxFeedPages bounds how many resource API pages are requested for a board.
+const maxFeedPages = 100
+
+// userAgent is sent with plain HTTP requests to Pinterest, which serves a
+// stripped page to unknown clients.
+const userAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36"
+
+// PinterestOptions control how Pinterest boards are read.
+type PinterestOptions struct {
+	Scraper string        // ScraperAuto, ScraperHTTP or ScraperBrowser
+	Timeout time.Duration // Limit for scrolling a board in headless Chrome
+}
+
+type pinterestSource struct {
+	url    string
+	opts   PinterestOptions
+	client *http.Client // pageClient when nil
+}
+
+func (s pinterestSource) Location() string { return s.url }
+
+func (s pinterestSource) Images(ctx context.Context) ([]remoteImage, error) {
+	switch s.opts.Scraper {
+	case ScraperBrowser:
+		return scrapeBoard(ctx, s.url, s.opts.Timeout)
+	case ScraperHTTP:
+		return s.fetchBoard(ctx)
+	}
+	pins, err := s.fetchBoard(ctx)
+	if err == nil && len(pins) > 0 {
+		return pins, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("no pins in page data")
+	}
+	logger.WithError(err).Info("Falling back to headless Chrome")
+	return scrapeBoard(ctx, s.url, s.opts.Timeout)
+}
+
+// pwsData finds the JSON page state Pinterest embeds in board pages.
+var pwsData = regexp.MustCompile(`(?s)<script[^>]*\bid="__PWS_(?:DATA|INITIAL_PROPS)__"[^>]*>(.*?)</script>`)
+
+// fetchBoard reads the pins embedded in the board page and then follows the
+// board feed's bookmarks through the resource API until it ends.
+func (s pinterestSource) fetchBoard(ctx context.Context) ([]remoteImage, error) {
+	board, err := url.Parse(s.url)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.get(ctx, board.String(), "text/html")
+	if err != nil {
+		return nil, err
+	}
+	m := pwsData.FindSubmatch(page)
+	if m == nil {
+		return nil, fmt.Errorf("no page data in %s", s.url)
+	}
+	var state any
+	if err := json.Unmarshal(m[1], &state); err != nil {
+		return nil, fmt.Errorf("failed to parse page data: %w", err)
+	}
+
+	var c pinCollector
+	c.walk(state)
+	boardID := c.boardID(board.Path)
+	bookmark := c.bookmark
+	logger.Infof("Page data has %d pins", len(c.images()))
+
+	for n := 0; boardID != "" && bookmark != "" && bookmark != "-end-" && n < maxFeedPages; n++ {
+		found := len(c.images())
+		var resp any
+		data, err := s.get(ctx, feedURL(board, boardID, bookmark), "application/json")
+		if err == nil {
+			err = json.Unmarshal(data, &resp)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch board feed: %w", err)
+		}
+		c.bookmark = ""
+		c.walk(resp)
+		logger.Infof("Feed page %d: %d pins", n+1, len(c.images()))
+		if len(c.images()) == found {
+			break
+		}
+		bookmark = c.bookmark
+	}
+	return c.images(), nil
+}
+
+// feedURL is the resource API request for the board feed page after bookmark.
+func feedURL(board *url.URL, boardID, bookmark string) string {
+	options, _ := json.Marshal(map[string]any{
+		"options": map[string]any{
+			"board_id":      boardID,
+			"board_url":     board.Path,
+			"page_size":     25,
+			"bookmarks":     []string{bookmark},
+			"field_set_key": "react_grid_pin",
+		},
+		"context": map[string]any{},
+	})
+	u := url.URL{Scheme: board.Scheme, Host: board.Host, Path: "/resource/BoardFeedResource/get/"}
+	u.RawQuery = url.Values{"source_url": {board.Path}, "data": {string(options)}}.Encode()
+	return u.String()
+}
+
+func (s pinterestSource) get(ctx context.Context, rawURL, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", accept)
+	if accept == "application/json" {
+		req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	}
+	client := s.client
+	if client == nil {
+		client = pageClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", rawURL, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// pinCollector walks Pinterest JSON, which nests pins, boards and bookmarks
+// differently from one page version to the next, and picks out what it
+// recognises wherever it is.
+type pinCollector struct {
+	pins     []remoteImage // Pins from lists, in board order
+	loose    []remoteImage // Pins only found in lookup tables keyed by ID
+	seen     map[string]bool
+	boards   map[string]string // Board URL path to ID
+	bookmark string
+}
+
+func (c *pinCollector) walk(v any) {
+	c.walkIn(v, false)
+}
+
+func (c *pinCollector) walkIn(v any, inList bool) {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			c.walkIn(e, true)
+		}
+	case map[string]any:
+		switch v["type"] {
+		case "pin":
+			c.addPin(v, inList)
+		case "board":
+			id, _ := v["id"].(string)
+			path, _ := v["url"].(string)
+			if id != "" && path != "" {
+				if c.boards == nil {
+					c.boards = map[string]string{}
+				}
+				c.boards[strings.Trim(path, "/")] = id
+			}
+		}
+		for _, key := range []string{"bookmark", "nextBookmark"} {
+			if b, ok := v[key].(string); ok && b != "" {
+				c.bookmark = b
+			}
+		}
+		if b, ok := v["bookmarks"].([]any); ok && len(b) > 0 {
+			if s, ok := b[0].(string); ok && s != "" {
+				c.bookmark = s
+			}
+		}
+		// Visit keys in order so pins kept in maps come out the same every run.
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			c.walkIn(v[k], false)
+		}
+	}
+}
+
+// images returns the pins found so far: those from lists first, as only
+// lists keep the board order.
+func (c *pinCollector) images() []remoteImage {
+	var out []remoteImage
+	seen := map[string]bool{}
+	for _, img := range append(c.pins, c.loose...) {
+		if !seen[img.Src] {
+			seen[img.Src] = true
+			out = append(out, img)
+		}
+	}
+	return out
+}
+
+// addPin records a pin's images: the smallest as Src, the thumbnail the
+// board shows, and all of them with their widths as Srcset.
+func (c *pinCollector) addPin(pin map[string]any, inList bool) {
+	id, _ := pin["id"].(string)
+	images, _ := pin["images"].(map[string]any)
+	if id == "" || len(images) == 0 || c.seen[id] {
+		return
+	}
+	type variant struct {
+		url   string
+		width float64
+	}
+	var variants []variant
+	for _, img := range images {
+		img, _ := img.(map[string]any)
+		u, _ := img["url"].(string)
+		w, _ := img["width"].(float64)
+		if u != "" {
+			variants = append(variants, variant{u, w})
+		}
+	}
+	if len(variants) == 0 {
+		return
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		if variants[i].width != variants[j].width {
+			return variants[i].width < variants[j].width
+		}
+		return variants[i].url < variants[j].url
+	})
+	var srcset []string
+	for _, v := range variants {
+		srcset = append(srcset, fmt.Sprintf("%s %dw", v.url, int(v.width)))
+	}
+	img := remoteImage{Src: variants[0].url, Srcset: strings.Join(srcset, ", "), pinMeta: pinMetaOf(pin)}
+	if !inList {
+		c.loose = append(c.loose, img)
+		return
+	}
+	if c.seen == nil {
+		c.seen = map[string]bool{}
+	}
+	c.seen[id] = true
+	c.pins = append(c.pins, img)
+}
+
+// pinMetaOf reads the metadata of a pin object.
+func pinMetaOf(pin map[string]any) pinMeta {
+	str := func(v any) string {
+		s, _ := v.(string)
+		return strings.TrimSpace(s)
+	}
+	meta := pinMeta{
+		ID:          str(pin["id"]),
+		Title:       str(pin["title"]),
+		Description: str(pin["description"]),
+		Link:        str(pin["link"]),
+		Color:       hexColor(str(pin["dominant_color"])),
+	}
+	if meta.Title == "" {
+		meta.Title = str(pin["grid_title"])
+	}
+	if section, ok := pin["board_section"].(map[string]any); ok {
+		meta.Section = str(section["title"])
+	}
+	return meta
+}
+
+// boardID finds the ID of the board at path.
+func (c *pinCollector) boardID(path string) string {
+	return c.boards[strings.Trim(path, "/")]
+}
@@ -0,0 +1,128 @@
+package gallery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// boardServer serves the recorded board page and its two feed pages, keyed
+// by the bookmark each request sends.
+func boardServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	pages := map[string]string{
+		"Y2JVSG81V2sxcmNHRlp":  "testdata/pinterest_feed_1.json",
+		"Y2JVSG81V2sxcmNHRlp2": "testdata/pinterest_feed_2.json",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/anna/moods/":
+			http.ServeFile(w, r, "testdata/pinterest_board.html")
+		case "/resource/BoardFeedResource/get/":
+			var data struct {
+				Options struct {
+					BoardID   string   `json:"board_id"`
+					Bookmarks []string `json:"bookmarks"`
+				} `json:"options"`
+			}
+			if err := json.Unmarshal([]byte(r.URL.Query().Get("data")), &data); err != nil || data.Options.BoardID != "900100" || len(data.Options.Bookmarks) != 1 {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			page, ok := pages[data.Options.Bookmarks[0]]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			body, err := os.ReadFile(page)
+			if err != nil {
+				t.Error(err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPinterestSource_HTTP(t *testing.T) {
+	srv := boardServer(t)
+	src := pinterestSource{url: srv.URL + "/anna/moods/", opts: PinterestOptions{Scraper: ScraperHTTP}, client: srv.Client()}
+
+	images, err := src.Images(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, img := range images {
+		got = append(got, img.Src)
+	}
+	want := []string{
+		"https://i.pinimg.com/236x/aa/01/pin1.jpg",
+		"https://i.pinimg.com/236x/bb/02/pin2.jpg",
+		"https://i.pinimg.com/236x/cc/03/pin3.jpg",
+		"https://i.pinimg.com/236x/dd/04/pin4.jpg",
+		"https://i.pinimg.com/236x/ee/05/pin5.jpg",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pins = %q\nwant %q", got, want)
+	}
+
+	wantMeta := pinMeta{ID: "100001", Title: "Linen kitchen", Description: "Warm oak and linen", Link: "https://example.com/kitchen", Color: "#c8b49a", Section: "Kitchen"}
+	if images[0].pinMeta != wantMeta {
+		t.Errorf("pin 1 = %+v\nwant %+v", images[0].pinMeta, wantMeta)
+	}
+	if meta := images[2].pinMeta; meta.Title != "Herb garden" || meta.Description != "" || meta.Section != "Garden" {
+		t.Errorf("pin 3 = %+v, want its grid title and section", meta)
+	}
+
+	// The rewritten original keeps the thumbnail's extension, so the orig
+	// image listed in the page data follows as the fallback.
+	if second := candidateURLs(images[2])[1]; second != "https://i.pinimg.com/originals/cc/03/pin3.png" {
+		t.Errorf("second candidate for pin 3 = %s, want its orig image", second)
+	}
+}
+
+func TestPinterestSource_HTTPWithoutPageData(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>Log in to see this board</body></html>"))
+	}))
+	defer srv.Close()
+
+	src := pinterestSource{url: srv.URL + "/anna/moods/", opts: PinterestOptions{Scraper: ScraperHTTP}, client: srv.Client()}
+	if _, err := src.Images(context.Background()); err == nil {
+		t.Error("page without page data gave no error")
+	}
+}
+
+func TestParseScraper(t *testing.T) {
+	for in, want := range map[string]string{"": ScraperAuto, "auto": ScraperAuto, " HTTP ": ScraperHTTP, "browser": ScraperBrowser} {
+		if got, err := ParseScraper(in); err != nil || got != want {
+			t.Errorf("ParseScraper(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseScraper("chrome"); err == nil {
+		t.Error("unknown scraper was accepted")
+	}
+}
+
+func TestPinCollector_LooseOnlyPins(t *testing.T) {
+	var state any
+	json.Unmarshal([]byte(`{"pins":{
+		"2":{"type":"pin","id":"2","images":{"236x":{"url":"https://i.pinimg.com/236x/2.jpg","width":236}}},
+		"1":{"type":"pin","id":"1","images":{"236x":{"url":"https://i.pinimg.com/236x/1.jpg","width":236}}}
+	}}`), &state)
+	var c pinCollector
+	c.walk(state)
+	images := c.images()
+	if len(images) != 2 || images[0].Src != "https://i.pinimg.com/236x/1.jpg" {
+		t.Errorf("images = %+v, want both pins in ID order", images)
+	}
+}
@@ -0,0 +1,53 @@
+package gallery
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// progressWidth is the number of cells in a progress bar.
+const progressWidth = 30
+
+// progress draws a one-line progress bar on a terminal, redrawn in place.
+// Without a known total it shows just the count. On anything other than a
+// terminal it stays silent and leaves reporting to the log.
+type progress struct {
+	w     io.Writer // nil when disabled
+	label string
+	drawn bool
+}
+
+func newProgress(label string) *progress {
+	p := &progress{label: label}
+	if info, err := os.Stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		p.w = os.Stderr
+	}
+	return p
+}
+
+func (p *progress) update(n, total int) {
+	if p.w == nil {
+		return
+	}
+	p.drawn = true
+	fmt.Fprint(p.w, "\r"+progressLine(p.label, n, total))
+}
+
+// done ends the bar's line so later output starts on a fresh one.
+func (p *progress) done() {
+	if p.w != nil && p.drawn {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// progressLine renders the bar for n of total, or just n when total is 0 or
+// already exceeded.
+func progressLine(label string, n, total int) string {
+	if total <= 0 || n > total {
+		return fmt.Sprintf("%s: %d found", label, n)
+	}
+	filled := n * progressWidth / total
+	return fmt.Sprintf("%s [%s%s] %d/%d", label, strings.Repeat("#", filled), strings.Repeat(".", progressWidth-filled), n, total)
+}
@@ -0,0 +1,36 @@
+package gallery
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		n, total int
+		want     string
+	}{
+		{0, 0, "Pins: 0 found"},
+		{42, 0, "Pins: 42 found"},
+		{15, 30, "Pins [" + strings.Repeat("#", 15) + strings.Repeat(".", 15) + "] 15/30"},
+		{30, 30, "Pins [" + strings.Repeat("#", 30) + "] 30/30"},
+		{31, 30, "Pins: 31 found"},
+	}
+	for _, tt := range tests {
+		if got := progressLine("Pins", tt.n, tt.total); got != tt.want {
+			t.Errorf("progressLine(%d, %d) = %q, want %q", tt.n, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestProgress_RedrawsInPlace(t *testing.T) {
+	var buf bytes.Buffer
+	p := &progress{w: &buf, label: "Pins"}
+	p.update(1, 0)
+	p.update(2, 0)
+	p.done()
+	if got := buf.String(); got != "\rPins: 1 found\rPins: 2 found\n" {
+		t.Errorf("output = %q", got)
+	}
+}
@@ -0,0 +1,393 @@
+package gallery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Source lists the images of a board, feed, page or URL list.
+type Source interface {
+	// Location is the URL or path read, used in logs and to name the manifest.
+	Location() string
+	// Images lists the images in the order they appear, without duplicates.
+	Images(ctx context.Context) ([]remoteImage, error)
+}
+
+// Source kinds accepted by NewSource.
+const (
+	SourceAuto      = "auto"
+	SourcePinterest = "pinterest"
+	SourceFeed      = "feed"
+	SourceHTML      = "html"
+	SourceList      = "list"
+)
+
+// NewSource opens location as kind, reading Pinterest boards with pin. With
+// SourceAuto the kind follows from the location: Pinterest URLs are boards,
+// feed: URLs and .rss, .atom or .xml paths are feeds, other web URLs and
+// .html files are pages, and any other file is a list of URLs.
+func NewSource(location, kind string, pin PinterestOptions) (Source, error) {
+	if u, ok := feedLocation(location); ok {
+		location = u
+		if kind == SourceAuto {
+			kind = SourceFeed
+		}
+	}
+	if kind == SourceAuto {
+		kind = detectSource(location)
+	}
+	switch kind {
+	case SourcePinterest:
+		return pinterestSource{url: location, opts: pin}, nil
+	case SourceFeed:
+		return feedSource{location: location}, nil
+	case SourceHTML:
+		return htmlSource{location: location}, nil
+	case SourceList:
+		return listSource{location: location}, nil
+	}
+	return nil, fmt.Errorf("invalid source type %q (use auto, pinterest, feed, html or list)", kind)
+}
+
+// feedLocation turns feed:http://, feed:https:// and feed:// locations into the
+// web URL they name. feed:// stands for https://.
+func feedLocation(location string) (string, bool) {
+	rest, ok := strings.CutPrefix(location, "feed:")
+	if !ok {
+		return "", false
+	}
+	lower := strings.ToLower(rest)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		return rest, true
+	}
+	return "https://" + strings.TrimPrefix(rest, "//"), true
+}
+
+func detectSource(location string) string {
+	ext := strings.ToLower(filepath.Ext(location))
+	u, err := url.Parse(location)
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		host := strings.ToLower(u.Hostname())
+		ext = strings.ToLower(filepath.Ext(u.Path))
+		switch {
+		case strings.Contains(host, "pinterest.") || host == "pin.it":
+			return SourcePinterest
+		case ext == ".rss" || ext == ".atom" || ext == ".xml" || strings.Contains(strings.ToLower(u.Path), "/feed"):
+			return SourceFeed
+		}
+		return SourceHTML
+	}
+	switch ext {
+	case ".rss", ".atom", ".xml":
+		return SourceFeed
+	case ".html", ".htm":
+		return SourceHTML
+	}
+	return SourceList
+}
+
+// feedSource reads the images of an RSS or Atom feed: Media RSS content and
+// thumbnails, image enclosures and <img> tags in item descriptions.
+type feedSource struct{ location string }
+
+func (s feedSource) Location() string { return s.location }
+
+func (s feedSource) Images(ctx context.Context) ([]remoteImage, error) {
+	data, base, err := readLocation(ctx, s.location)
+	if err != nil {
+		return nil, err
+	}
+	images, err := feedImages(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feed %s: %w", s.location, err)
+	}
+	return resolveImages(images, base), nil
+}
+
+// htmlSource reads the <img> tags of a web page or saved HTML file. Relative
+// images of a saved file are read from the folder next to it.
+type htmlSource struct{ location string }
+
+func (s htmlSource) Location() string { return s.location }
+
+func (s htmlSource) Images(ctx context.Context) ([]remoteImage, error) {
+	data, base, err := readLocation(ctx, s.location)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil {
+		path := s.location
+		if u, err := url.Parse(path); err == nil && u.Scheme == "file" {
+			path = filepath.FromSlash(u.Path)
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			base = fileURL(abs)
+		}
+	}
+	if m := baseHref.FindSubmatch(data); m != nil {
+		if b, err := resolveURL(base, html.UnescapeString(string(bytes.Join(m[1:], nil)))); err == nil {
+			base = b
+		}
+	}
+	return resolveImages(htmlImages(string(data)), base), nil
+}
+
+// listSource reads a text file with one image URL per line. Blank lines and
+// lines starting with # are skipped.
+type listSource struct{ location string }
+
+func (s listSource) Location() string { return s.location }
+
+func (s listSource) Images(ctx context.Context) ([]remoteImage, error) {
+	data, base, err := readLocation(ctx, s.location)
+	if err != nil {
+		return nil, err
+	}
+	var images []remoteImage
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		images = append(images, remoteImage{Src: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return resolveImages(images, base), nil
+}
+
+// pageClient fetches feeds, pages and lists given as URLs.
+var pageClient = &http.Client{Timeout: 30 * time.Second}
+
+// readLocation reads a URL or local file. For URLs it also returns the URL
+// that relative links resolve against; for files that is nil.
+func readLocation(ctx context.Context, location string) ([]byte, *url.URL, error) {
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		if err == nil && u.Scheme == "file" {
+			location = filepath.FromSlash(u.Path)
+		}
+		data, err := os.ReadFile(location)
+		return data, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := pageClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s: %s", location, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return data, resp.Request.URL, err
+}
+
+// resolveImages makes image URLs absolute against base and drops duplicates
+// and anything that is not an http or https URL, or a file URL when base is
+// a local file.
+func resolveImages(images []remoteImage, base *url.URL) []remoteImage {
+	var out []remoteImage
+	seen := map[string]bool{}
+	skipped := 0
+	for _, img := range images {
+		src, err := resolveURL(base, img.Src)
+		local := src != nil && src.Scheme == "file" && base != nil && base.Scheme == "file"
+		if err != nil || (src.Scheme != "http" && src.Scheme != "https" && !local) {
+			skipped++
+			continue
+		}
+		img.Src = src.String()
+		if seen[img.Src] {
+			continue
+		}
+		seen[img.Src] = true
+
+		var srcset []string
+		for _, candidate := range strings.Split(img.Srcset, ",") {
+			fields := strings.Fields(candidate)
+			if len(fields) == 0 {
+				continue
+			}
+			if u, err := resolveURL(base, fields[0]); err == nil {
+				fields[0] = u.String()
+				srcset = append(srcset, strings.Join(fields, " "))
+			}
+		}
+		img.Srcset = strings.Join(srcset, ", ")
+		out = append(out, img)
+	}
+	if skipped > 0 {
+		logger.Warnf("Skipped %d images without a web address or local file", skipped)
+	}
+	return out
+}
+
+// fileURL is the file URL of an absolute path.
+func fileURL(path string) *url.URL {
+	return &url.URL{Scheme: "file", Path: "/" + strings.TrimPrefix(filepath.ToSlash(path), "/")}
+}
+
+func resolveURL(base *url.URL, ref string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || base == nil {
+		return u, err
+	}
+	return base.ResolveReference(u), nil
+}
+
+var (
+	imgTag   = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+	baseHref = regexp.MustCompile(`(?is)<base\b[^>]*?\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	htmlAttr = regexp.MustCompile(`(?s)([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// htmlImages lists the <img> tags of an HTML document. Lazy-loading
+// data-src and data-srcset attributes stand in for missing src and srcset.
+func htmlImages(doc string) []remoteImage {
+	var images []remoteImage
+	for _, tag := range imgTag.FindAllString(doc, -1) {
+		attrs := map[string]string{}
+		for _, m := range htmlAttr.FindAllStringSubmatch(tag[len("<img"):], -1) {
+			name := strings.ToLower(m[1])
+			if _, ok := attrs[name]; !ok {
+				attrs[name] = html.UnescapeString(m[2] + m[3] + m[4])
+			}
+		}
+		img := remoteImage{Src: attrs["src"], Srcset: attrs["srcset"]}
+		if img.Src == "" || strings.HasPrefix(img.Src, "data:") {
+			img.Src = attrs["data-src"]
+		}
+		if img.Srcset == "" {
+			img.Srcset = attrs["data-srcset"]
+		}
+		if img.Src == "" {
+			img.Src = largestSrcset(img.Srcset)
+		}
+		if img.Src != "" {
+			images = append(images, img)
+		}
+	}
+	return images
+}
+
+// mediaRSS is the Media RSS namespace used for media:content and
+// media:thumbnail.
+const mediaRSS = "http://search.yahoo.com/mrss/"
+
+// feedImages lists the images of the items of an RSS or Atom feed. Each item
+// contributes its Media RSS content and image enclosures, or else the images
+// in its HTML, or else its thumbnail.
+func feedImages(data []byte) ([]remoteImage, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var images, media, embedded, thumbs []remoteImage
+	inItem := false
+	flush := func() {
+		switch {
+		case len(media) > 0:
+			images = append(images, media...)
+		case len(embedded) > 0:
+			images = append(images, embedded...)
+		default:
+			images = append(images, thumbs...)
+		}
+		media, embedded, thumbs = nil, nil, nil
+	}
+	sawRoot := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			attr := func(name string) string {
+				for _, a := range t.Attr {
+					if a.Name.Local == name {
+						return strings.TrimSpace(a.Value)
+					}
+				}
+				return ""
+			}
+			local := strings.ToLower(t.Name.Local)
+			switch {
+			case local == "rss" || local == "feed" || local == "rdf":
+				sawRoot = true
+			case local == "item" || local == "entry":
+				inItem = true
+			case !inItem:
+				// Channel-level media such as the feed logo are not items.
+			case t.Name.Space == mediaRSS && local == "content":
+				if isImage(attr("medium"), attr("type"), attr("url")) {
+					media = append(media, remoteImage{Src: attr("url")})
+				}
+			case t.Name.Space == mediaRSS && local == "thumbnail":
+				thumbs = append(thumbs, remoteImage{Src: attr("url")})
+			case local == "enclosure":
+				if strings.HasPrefix(attr("type"), "image/") {
+					media = append(media, remoteImage{Src: attr("url")})
+				}
+			case local == "link" && attr("rel") == "enclosure":
+				if strings.HasPrefix(attr("type"), "image/") {
+					media = append(media, remoteImage{Src: attr("href")})
+				}
+			case local == "description" || local == "encoded" || local == "summary" || local == "content":
+				var text string
+				if err := dec.DecodeElement(&text, &t); err != nil {
+					return nil, err
+				}
+				embedded = append(embedded, htmlImages(text)...)
+			}
+		case xml.EndElement:
+			if local := strings.ToLower(t.Name.Local); local == "item" || local == "entry" {
+				flush()
+				inItem = false
+			}
+		}
+	}
+	if !sawRoot {
+		return nil, fmt.Errorf("not an RSS or Atom feed")
+	}
+	return images, nil
+}
+
+// isImage reports whether a media:content element is an image, going by
+// its medium, its MIME type or else its file extension.
+func isImage(medium, typ, src string) bool {
+	switch {
+	case medium != "":
+		return medium == "image"
+	case typ != "":
+		return strings.HasPrefix(typ, "image/")
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mime.TypeByExtension(strings.ToLower(filepath.Ext(u.Path))), "image/")
+}
@@ -0,0 +1,132 @@
+package gallery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func sourceSrcs(t *testing.T, src Source) []string {
+	t.Helper()
+	images, err := src.Images(context.Background())
+	if err != nil {
+		t.Fatalf("%s: %v", src.Location(), err)
+	}
+	var srcs []string
+	for _, img := range images {
+		srcs = append(srcs, img.Src)
+	}
+	return srcs
+}
+
+func TestNewSource_Detects(t *testing.T) {
+	tests := map[string]Source{
+		"https://www.pinterest.com/someone/board/": pinterestSource{url: "https://www.pinterest.com/someone/board/", opts: PinterestOptions{Scraper: ScraperAuto}},
+		"https://pin.it/abc":                       pinterestSource{url: "https://pin.it/abc", opts: PinterestOptions{Scraper: ScraperAuto}},
+		"https://example.com/blog/feed":            feedSource{location: "https://example.com/blog/feed"},
+		"feed://example.com/index.xml":             feedSource{location: "https://example.com/index.xml"},
+		"feed:http://example.com/rss":              feedSource{location: "http://example.com/rss"},
+		"feed:https://example.com/rss":             feedSource{location: "https://example.com/rss"},
+		"https://example.com/gallery":              htmlSource{location: "https://example.com/gallery"},
+		"saved/page.HTML":                          htmlSource{location: "saved/page.HTML"},
+		"export.atom":                              feedSource{location: "export.atom"},
+		"urls.txt":                                 listSource{location: "urls.txt"},
+	}
+	for location, want := range tests {
+		got, err := NewSource(location, SourceAuto, PinterestOptions{Scraper: ScraperAuto})
+		if err != nil || got != want {
+			t.Errorf("NewSource(%q) = %#v, %v; want %#v", location, got, err, want)
+		}
+	}
+	if got, _ := NewSource("urls.txt", SourceHTML, PinterestOptions{}); got != (htmlSource{location: "urls.txt"}) {
+		t.Errorf("explicit type was ignored: %#v", got)
+	}
+	if _, err := NewSource("urls.txt", "gopher", PinterestOptions{}); err == nil {
+		t.Error("unknown source type was accepted")
+	}
+}
+
+func TestFeedSource_RSS(t *testing.T) {
+	got := sourceSrcs(t, feedSource{location: "testdata/feed.rss"})
+	// The relative embedded image has no base in a local file and is skipped.
+	want := []string{"https://example.com/a.jpg", "https://example.com/b.png", "https://example.com/d.jpg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("images = %q, want %q", got, want)
+	}
+}
+
+func TestFeedSource_Atom(t *testing.T) {
+	got := sourceSrcs(t, feedSource{location: "testdata/feed.atom"})
+	want := []string{"https://example.com/e.jpg", "https://example.com/f.jpg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("images = %q, want %q", got, want)
+	}
+}
+
+func TestFeedSource_ResolvesAgainstFeedURL(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	images, err := feedSource{location: srv.URL + "/feed.rss"}.Images(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 4 {
+		t.Fatalf("found %d images, want 4", len(images))
+	}
+	embedded := images[2]
+	if embedded.Src != srv.URL+"/c.jpg" || embedded.Srcset != srv.URL+"/c.jpg 1x, "+srv.URL+"/c@2x.jpg 2x" {
+		t.Errorf("embedded image = %+v", embedded)
+	}
+}
+
+func TestHTMLSource(t *testing.T) {
+	images, err := htmlSource{location: "testdata/page.html"}.Images(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []remoteImage{
+		{Src: "https://example.com/gallery/one.jpg"},
+		{Src: "https://cdn.example.com/two.jpg", Srcset: "https://cdn.example.com/two.jpg 400w, https://cdn.example.com/two_big.jpg 1200w"},
+		{Src: "https://example.com/gallery/three.jpg"},
+		{Src: "https://example.com/gallery/a.jpg?w=1&h=2"},
+	}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("images = %+v\nwant %+v", images, want)
+	}
+	if got := candidateURLs(images[1])[0]; got != "https://cdn.example.com/two_big.jpg" {
+		t.Errorf("best candidate = %s, want the largest srcset entry", got)
+	}
+}
+
+func TestHTMLSource_SavedPageReadsLocalImages(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.html")
+	html := `<img src="page_files/a%20b.jpg"><img src="https://cdn.example.com/c.jpg"><img src="file:///etc/hosts">`
+	if err := os.WriteFile(page, []byte(html), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := sourceSrcs(t, htmlSource{location: page})
+	want := []string{fileURL(filepath.Join(dir, "page_files", "a b.jpg")).String(), "https://cdn.example.com/c.jpg", "file:///etc/hosts"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("images = %q, want %q", got, want)
+	}
+	// Local files are only read when the page itself is local.
+	remote := resolveImages([]remoteImage{{Src: "file:///etc/hosts"}}, &url.URL{Scheme: "https", Host: "example.com"})
+	if len(remote) != 0 {
+		t.Errorf("web page was allowed to read local files: %+v", remote)
+	}
+}
+
+func TestListSource(t *testing.T) {
+	got := sourceSrcs(t, listSource{location: "testdata/urls.txt"})
+	want := []string{"https://example.com/x.jpg", "https://example.com/y.jpg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("images = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package gallery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// syncManifest is the manifest of a mirrored board in its directory.
+const syncManifest = "manifest.json"
+
+// syncStats counts what a sync changed.
+type syncStats struct {
+	Added, Kept, Removed, Restored, Failed int
+}
+
+func (s syncStats) String() string {
+	return fmt.Sprintf("%d new, %d kept, %d removed, %d back on the board, %d failed", s.Added, s.Kept, s.Removed, s.Restored, s.Failed)
+}
+
+// SyncBoard mirrors a Pinterest board into dir; see syncSource.
+func SyncBoard(boardURL, dir string, opts PinterestOptions, d *Downloader) (syncStats, error) {
+	return syncSource(context.Background(), pinterestSource{url: boardURL, opts: opts}, dir, d)
+}
+
+// syncSource brings the mirror of src in dir up to date. Pins already in the
+// mirror are not downloaded again, new ones are saved as pin_<ID> and pins
+// gone from the source are kept on disk but marked removed. The manifest
+// lists current pins in source order, followed by removed ones.
+func syncSource(ctx context.Context, src Source, dir string, d *Downloader) (syncStats, error) {
+	var stats syncStats
+	path := filepath.Join(dir, syncManifest)
+	old, err := readManifest(path)
+	if err != nil && !os.IsNotExist(err) {
+		return stats, err
+	}
+
+	images, err := src.Images(ctx)
+	if err != nil {
+		return stats, err
+	}
+	if len(images) == 0 && len(old.Pins) > 0 {
+		return stats, fmt.Errorf("%s returned no pins; leaving the %d pins of the mirror as they are", src.Location(), len(old.Pins))
+	}
+
+	known := map[string]manifestEntry{}
+	for _, pin := range old.Pins {
+		known[pin.ID] = pin
+	}
+
+	// Pins in source order; entries holds those that are on disk or were
+	// mirrored before.
+	var order []string
+	listed := map[string]bool{}
+	entries := map[string]manifestEntry{}
+	var jobs [][]string
+	var pending []manifestEntry
+	for _, img := range images {
+		id := pinID(img)
+		if listed[id] {
+			continue
+		}
+		listed[id] = true
+		order = append(order, id)
+		entry := manifestEntry{Thumbnail: img.Src, pinMeta: img.pinMeta}
+		entry.ID = id
+		if prev, ok := known[id]; ok && fileExists(filepath.Join(dir, prev.File)) {
+			entry.File, entry.URL = prev.File, prev.URL
+			if prev.Removed != "" {
+				stats.Restored++
+			} else {
+				stats.Kept++
+			}
+			entries[id] = entry
+			continue
+		}
+		pending = append(pending, entry)
+		jobs = append(jobs, candidateURLs(img))
+	}
+
+	// A failed pin that was mirrored before keeps its old entry, so that its
+	// file is fetched again on the next sync.
+	fail := func(entry manifestEntry) {
+		stats.Failed++
+		if prev, ok := known[entry.ID]; ok {
+			entry.File, entry.URL = prev.File, prev.URL
+			entries[entry.ID] = entry
+		}
+	}
+	for i, r := range d.FetchAll(ctx, jobs) {
+		entry := pending[i]
+		if r.Err != nil {
+			logger.WithError(r.Err).Warnf("Failed to download pin %s", entry.ID)
+			fail(entry)
+			continue
+		}
+		file := "pin_" + entry.ID + filepath.Ext(r.Path)
+		if err := linkOrCopy(r.Path, filepath.Join(dir, file)); err != nil {
+			logger.WithError(err).Warnf("Failed to save pin %s", entry.ID)
+			fail(entry)
+			continue
+		}
+		entry.File, entry.URL = file, r.URL
+		logger.Infof("Saved: %s", entry.File)
+		entries[entry.ID] = entry
+		stats.Added++
+	}
+
+	m := manifest{Source: src.Location()}
+	for _, id := range order {
+		if entry, ok := entries[id]; ok {
+			m.Pins = append(m.Pins, entry)
+		}
+	}
+
+	today := time.Now().Format("2006-01-02")
+	for _, pin := range old.Pins {
+		if listed[pin.ID] {
+			continue
+		}
+		if pin.Removed == "" {
+			pin.Removed = today
+			stats.Removed++
+			logger.Infof("No longer on the board: %s", pin.File)
+		}
+		m.Pins = append(m.Pins, pin)
+	}
+
+	if err := writeManifest(path, m); err != nil {
+		return stats, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return stats, nil
+}
+
+var unsafeIDChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// pinID names a pin in the mirror: its Pinterest ID, or else a hash of its
+// image URL.
+func pinID(img remoteImage) string {
+	if id := unsafeIDChars.ReplaceAllString(img.ID, ""); id != "" {
+		return id
+	}
+	return cacheKey(img.Src)[:12]
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// linkOrCopy puts the cached file src at dst, hard-linked when the two are on
+// the same file system.
+func linkOrCopy(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	os.Remove(dst)
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
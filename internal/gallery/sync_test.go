@@ -0,0 +1,139 @@
+package gallery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSource lists fixed images.
+type fakeSource []remoteImage
+
+func (s fakeSource) Location() string { return "https://www.pinterest.com/anna/moods/" }
+
+func (s fakeSource) Images(ctx context.Context) ([]remoteImage, error) { return s, nil }
+
+func pin(id, src string) remoteImage {
+	return remoteImage{Src: src, pinMeta: pinMeta{ID: id}}
+}
+
+func TestSyncSource(t *testing.T) {
+	srv, counts := imageServer(t)
+	d := testDownloader(t)
+	dir := t.TempDir()
+
+	stats, err := syncSource(context.Background(), fakeSource{pin("1", srv.URL+"/ok"), pin("2", srv.URL+"/flaky")}, dir, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (syncStats{Added: 2}) {
+		t.Errorf("first sync = %+v, want 2 added", stats)
+	}
+	for _, name := range []string{"pin_1.png", "pin_2.png"} {
+		if !fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s was not saved", name)
+		}
+	}
+
+	// Pin 1 left the board, pin 3 is new and comes first.
+	okBefore := *counts["/ok"]
+	stats, err = syncSource(context.Background(), fakeSource{pin("3", srv.URL+"/ok?3"), pin("2", srv.URL+"/flaky")}, dir, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (syncStats{Added: 1, Kept: 1, Removed: 1}) {
+		t.Errorf("second sync = %+v", stats)
+	}
+	if *counts["/flaky"] != 3 || *counts["/ok"] != okBefore+1 {
+		t.Errorf("kept pins were downloaded again: flaky %d, ok %d", *counts["/flaky"], *counts["/ok"])
+	}
+
+	m, err := readManifest(filepath.Join(dir, syncManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids, removed []string
+	for _, p := range m.Pins {
+		ids = append(ids, p.ID)
+		removed = append(removed, p.Removed)
+	}
+	if len(ids) != 3 || ids[0] != "3" || ids[1] != "2" || ids[2] != "1" {
+		t.Fatalf("manifest order = %v, want 3 2 1", ids)
+	}
+	if removed[0] != "" || removed[1] != "" || removed[2] == "" {
+		t.Errorf("removed marks = %q, want only pin 1", removed)
+	}
+	if !fileExists(filepath.Join(dir, "pin_1.png")) {
+		t.Error("removed pin was deleted from disk")
+	}
+
+	in, err := LoadManifest(filepath.Join(dir, syncManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in.Paths) != 2 {
+		t.Errorf("gallery input = %q, want the two current pins", in.Paths)
+	}
+
+	// Pin 1 returns; its file is still there.
+	stats, err = syncSource(context.Background(), fakeSource{pin("1", srv.URL+"/ok"), pin("3", srv.URL+"/ok?3"), pin("2", srv.URL+"/flaky")}, dir, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (syncStats{Kept: 2, Restored: 1}) {
+		t.Errorf("third sync = %+v", stats)
+	}
+}
+
+func TestSyncSource_KeepsMirrorWhenBoardIsEmpty(t *testing.T) {
+	srv, _ := imageServer(t)
+	dir := t.TempDir()
+	d := testDownloader(t)
+	if _, err := syncSource(context.Background(), fakeSource{pin("1", srv.URL+"/ok")}, dir, d); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(filepath.Join(dir, syncManifest))
+	if _, err := syncSource(context.Background(), fakeSource{}, dir, d); err == nil {
+		t.Error("empty board gave no error")
+	}
+	after, _ := os.ReadFile(filepath.Join(dir, syncManifest))
+	if string(before) != string(after) {
+		t.Error("manifest changed after an empty scrape")
+	}
+}
+
+func TestSyncSource_KeepsEntryWhenRedownloadFails(t *testing.T) {
+	srv, _ := imageServer(t)
+	dir := t.TempDir()
+	d := testDownloader(t)
+	if _, err := syncSource(context.Background(), fakeSource{pin("1", srv.URL+"/ok")}, dir, d); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "pin_1.png")); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := syncSource(context.Background(), fakeSource{pin("1", srv.URL+"/missing")}, dir, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (syncStats{Failed: 1}) {
+		t.Errorf("sync = %+v, want 1 failed", stats)
+	}
+	m, err := readManifest(filepath.Join(dir, syncManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Pins) != 1 || m.Pins[0].ID != "1" || m.Pins[0].File != "pin_1.png" || m.Pins[0].Removed != "" {
+		t.Errorf("manifest = %+v, want pin 1 carried forward", m.Pins)
+	}
+}
+
+func TestPinID(t *testing.T) {
+	if got := pinID(pin("../12 34", "x")); got != "1234" {
+		t.Errorf("pinID = %q, want unsafe characters dropped", got)
+	}
+	if got := pinID(remoteImage{Src: "https://example.com/a.jpg"}); len(got) != 12 {
+		t.Errorf("pinID without ID = %q, want a 12 character hash", got)
+	}
+}
@@ -21,7 +21,6 @@ var defaultExclusions = []string{
 	"node_modules/...",
 	"vendor/...",
 	"go.sum",
-	"vendor/...",
 }
 
 var Cmd = &cobra.Command{
@@ -32,17 +31,24 @@ var Cmd = &cobra.Command{
 ⚠️ When using wildcards (* or ...), wrap arguments in quotes to prevent shell expansion.
 
 Go-style file selection examples:
-  ".*"             # All files in the current directory
-  "./.../*"        # All files in current directory and all subdirectories
-  "./.../*.go"     # All .go files in subdirectories
+  "./*"            # All files in the current directory
+  "./..."          # All files in current directory and all subdirectories
+  "./.../*.go"     # All .go files in current directory and subdirectories
   "./.../*.md"     # All Markdown files
-  "./config*"         # Files starting with 'config' in current dir
-  "./.../config*"     # Files starting with 'config' in all subdirs
+  "./config*"      # Files starting with 'config' in current dir
+  "./.../config*"  # Files starting with 'config' in all subdirs
+
+Exclusion patterns use the same syntax. A pattern without a slash matches at
+any depth ("node_modules/...", "*.log"), a leading "./" anchors it to the
+current directory, and "!" re-includes files excluded by an earlier pattern.
+Excluded directories are not walked, so their files cannot be re-included.
 
 Examples:
   grab "./.../*.txt"
   grab "./*.go"
   grab "./apps/kyc-service/..." -e "node_modules/..." -l
+  grab "./internal/.../*.go" --outline
+  grab "./internal/grab/*.go" --symbol RunWorker
 `,
 	Args: cobra.MinimumNArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -60,6 +66,9 @@ Examples:
 
 func init() {
 	Cmd.Flags().BoolVarP(&config.ListOnly, "list", "l", false, "Output list of files only")
-	Cmd.Flags().StringSliceVarP(&config.ExcludePatterns, "exclude", "e", []string{}, "Exclude files matching the provided wildcard patterns")
+	Cmd.Flags().StringSliceVarP(&config.ExcludePatterns, "exclude", "e", []string{}, "Exclude files matching the provided wildcard patterns (prefix with ! to re-include)")
 	Cmd.Flags().BoolVarP(&config.ExtraExclusions, "exclude-defaults", "x", false, "Exclude common directories and files such as .git, node_modules, dist, and build")
+	Cmd.Flags().BoolVar(&config.IncludeBinary, "include-binary", false, "List binary files with their size and MIME type instead of skipping them")
+	Cmd.Flags().BoolVar(&config.Outline, "outline", false, "For .go files output package, types and function signatures with doc comments, without bodies")
+	Cmd.Flags().StringVar(&config.Symbol, "symbol", "", "Output a single Go function or type (Name or Type.Method) with its dependencies in the same package")
 }
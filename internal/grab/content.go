@@ -0,0 +1,109 @@
+package grab
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// sniffSize is how much of a file is inspected before deciding whether it is
+// text, so large binaries are never read in full.
+const sniffSize = 8 << 10
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+type fileContent struct {
+	Text   string
+	Binary bool
+	Size   int64
+	MIME   string
+}
+
+// readContent reads a file as UTF-8 text with LF line endings. Files with a
+// UTF-16 byte order mark are transcoded; anything else that looks binary in
+// its first sniffSize bytes is reported as Binary without reading the rest.
+func readContent(path string) (fileContent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileContent{}, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fileContent{}, err
+	}
+	fc := fileContent{Size: fi.Size()}
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fileContent{}, err
+	}
+	head = head[:n]
+
+	var decode func([]byte) string
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		decode = func(b []byte) string { return string(b[len(bomUTF8):]) }
+	case bytes.HasPrefix(head, bomUTF16LE):
+		decode = func(b []byte) string { return decodeUTF16(b[len(bomUTF16LE):], false) }
+	case bytes.HasPrefix(head, bomUTF16BE):
+		decode = func(b []byte) string { return decodeUTF16(b[len(bomUTF16BE):], true) }
+	case looksBinary(head):
+		fc.Binary = true
+		fc.MIME = http.DetectContentType(head)
+		return fc, nil
+	default:
+		decode = func(b []byte) string { return string(b) }
+	}
+
+	rest, err := io.ReadAll(f)
+	if err != nil {
+		return fileContent{}, err
+	}
+	fc.Text = normaliseLineEndings(decode(append(head, rest...)))
+	fc.MIME = "text/plain; charset=utf-8"
+	return fc, nil
+}
+
+// looksBinary reports whether data contains a NUL or a control character that
+// does not appear in text files. Tab, line feed, vertical tab, form feed,
+// carriage return and escape are allowed.
+func looksBinary(data []byte) bool {
+	for _, b := range data {
+		switch {
+		case b == '\t', b == '\n', b == '\v', b == '\f', b == '\r', b == 0x1B:
+		case b < 0x20:
+			return true
+		}
+	}
+	return false
+}
+
+func decodeUTF16(b []byte, bigEndian bool) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		} else {
+			units[i] = uint16(b[2*i+1])<<8 | uint16(b[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+func normaliseLineEndings(s string) string {
+	if !strings.Contains(s, "\r") {
+		return s
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
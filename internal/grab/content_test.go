@@ -0,0 +1,96 @@
+package grab
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	return p
+}
+
+func TestReadContent_UTF16LEWithBOM(t *testing.T) {
+	t.Parallel()
+
+	data := []byte{0xFF, 0xFE, 'h', 0, 'i', 0, '\r', 0, '\n', 0, 0xE9, 0}
+	fc, err := readContent(writeTempFile(t, "utf16.txt", data))
+	if err != nil {
+		t.Fatalf("readContent failed: %v", err)
+	}
+	if fc.Binary {
+		t.Fatalf("expected UTF-16 file to be treated as text")
+	}
+	if fc.Text != "hi\né" {
+		t.Fatalf("unexpected text: %q", fc.Text)
+	}
+}
+
+func TestReadContent_UTF16BEWithBOM(t *testing.T) {
+	t.Parallel()
+
+	data := []byte{0xFE, 0xFF, 0, 'o', 0, 'k'}
+	fc, err := readContent(writeTempFile(t, "utf16be.txt", data))
+	if err != nil {
+		t.Fatalf("readContent failed: %v", err)
+	}
+	if fc.Text != "ok" {
+		t.Fatalf("unexpected text: %q", fc.Text)
+	}
+}
+
+func TestReadContent_StripsUTF8BOMAndNormalisesLineEndings(t *testing.T) {
+	t.Parallel()
+
+	data := append([]byte{0xEF, 0xBB, 0xBF}, []byte("a\r\nb\rc\n\fd")...)
+	fc, err := readContent(writeTempFile(t, "bom.txt", data))
+	if err != nil {
+		t.Fatalf("readContent failed: %v", err)
+	}
+	if fc.Text != "a\nb\nc\n\fd" {
+		t.Fatalf("unexpected text: %q", fc.Text)
+	}
+}
+
+func TestReadContent_BinaryDetectedFromHead(t *testing.T) {
+	t.Parallel()
+
+	// Text after the sniffed head must not change the verdict.
+	data := append([]byte("\x89PNG\r\n\x1a\n\x00\x00"), bytes.Repeat([]byte("a"), 2*sniffSize)...)
+	fc, err := readContent(writeTempFile(t, "image.png", data))
+	if err != nil {
+		t.Fatalf("readContent failed: %v", err)
+	}
+	if !fc.Binary {
+		t.Fatalf("expected binary file")
+	}
+	if fc.Size != int64(len(data)) {
+		t.Fatalf("expected size %d, got %d", len(data), fc.Size)
+	}
+	if fc.MIME != "image/png" {
+		t.Fatalf("expected image/png, got %q", fc.MIME)
+	}
+	if fc.Text != "" {
+		t.Fatalf("expected no text for binary file")
+	}
+}
+
+func TestReadContent_LargeTextReadInFull(t *testing.T) {
+	t.Parallel()
+
+	data := strings.Repeat("line\n", sniffSize)
+	fc, err := readContent(writeTempFile(t, "big.txt", []byte(data)))
+	if err != nil {
+		t.Fatalf("readContent failed: %v", err)
+	}
+	if fc.Text != data {
+		t.Fatalf("expected %d bytes of text, got %d", len(data), len(fc.Text))
+	}
+}
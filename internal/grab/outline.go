@@ -0,0 +1,299 @@
+package grab
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// goPrinter matches gofmt output.
+var goPrinter = printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+
+// outlineGo renders package clause, type declarations and function signatures
+// (with their doc comments) of a Go source file, dropping function bodies.
+func outlineGo(path string, src []byte) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	writeDoc(&buf, file.Doc)
+	fmt.Fprintf(&buf, "package %s\n", file.Name.Name)
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			buf.WriteString("\n")
+			writeDoc(&buf, d.Doc)
+			stripped := *d
+			stripped.Doc = nil
+			if err := goPrinter.Fprint(&buf, fset, &stripped); err != nil {
+				return "", err
+			}
+			buf.WriteString("\n")
+		case *ast.FuncDecl:
+			buf.WriteString("\n")
+			writeDoc(&buf, d.Doc)
+			stripped := *d
+			stripped.Doc = nil
+			stripped.Body = nil
+			if err := goPrinter.Fprint(&buf, fset, &stripped); err != nil {
+				return "", err
+			}
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String(), nil
+}
+
+func writeDoc(buf *bytes.Buffer, doc *ast.CommentGroup) {
+	if doc == nil {
+		return
+	}
+	for _, c := range doc.List {
+		buf.WriteString(c.Text)
+		buf.WriteString("\n")
+	}
+}
+
+type goDecl struct {
+	name string
+	file *ast.File
+	path string
+	node ast.Decl
+}
+
+// goPackage holds package-level declarations of one package in a directory,
+// keyed by name; methods are keyed as Recv.Name.
+type goPackage struct {
+	fset  *token.FileSet
+	decls map[string]*goDecl
+}
+
+// parseGoPackage parses the files of package pkgName among paths.
+func parseGoPackage(paths []string, pkgName string) (*goPackage, error) {
+	pkg := &goPackage{fset: token.NewFileSet(), decls: map[string]*goDecl{}}
+	for _, p := range paths {
+		path := filepath.ToSlash(p)
+		file, err := parser.ParseFile(pkg.fset, path, nil, parser.ParseComments)
+		if err != nil || file.Name.Name != pkgName {
+			continue
+		}
+		for _, decl := range file.Decls {
+			for _, name := range declNames(decl) {
+				pkg.decls[name] = &goDecl{name: name, file: file, path: path, node: decl}
+			}
+		}
+	}
+	return pkg, nil
+}
+
+// goSources keeps the Go files of paths that build for the current platform.
+// Test files are kept only when named in inputs, not matched by a directory
+// or pattern.
+func goSources(paths, inputs []string) []string {
+	named := map[string]bool{}
+	for _, in := range inputs {
+		named[filepath.Clean(in)] = true
+	}
+	var sources []string
+	for _, p := range paths {
+		if filepath.Ext(p) != ".go" {
+			continue
+		}
+		if strings.HasSuffix(p, "_test.go") && !named[filepath.Clean(p)] {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(filepath.Dir(p), filepath.Base(p)); err != nil || !ok {
+			continue
+		}
+		sources = append(sources, p)
+	}
+	return sources
+}
+
+func declNames(decl ast.Decl) []string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return []string{receiverName(d.Recv.List[0].Type) + "." + d.Name.Name}
+		}
+		return []string{d.Name.Name}
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					names = append(names, n.Name)
+				}
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// resolve returns the named declaration followed by every package-level
+// declaration it references, directly or transitively. References are matched
+// by identifier name only, so a local variable shadowing a package-level name
+// pulls that declaration in as well.
+func (p *goPackage) resolve(symbol string) []*goDecl {
+	root, ok := p.decls[symbol]
+	if !ok {
+		return nil
+	}
+
+	seen := map[ast.Decl]bool{root.node: true}
+	result := []*goDecl{root}
+	for i := 0; i < len(result); i++ {
+		for _, name := range referencedNames(result[i].node) {
+			dep, ok := p.decls[name]
+			if !ok || seen[dep.node] {
+				continue
+			}
+			seen[dep.node] = true
+			result = append(result, dep)
+		}
+	}
+	return result
+}
+
+func referencedNames(decl ast.Decl) []string {
+	seen := map[string]bool{}
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil && len(fn.Recv.List) > 0 {
+		recv := receiverName(fn.Recv.List[0].Type)
+		// Methods called on the receiver belong to the same type.
+		if len(fn.Recv.List[0].Names) > 0 {
+			recvVar := fn.Recv.List[0].Names[0].Name
+			ast.Inspect(fn, func(n ast.Node) bool {
+				if sel, ok := n.(*ast.SelectorExpr); ok {
+					if id, ok := sel.X.(*ast.Ident); ok && id.Name == recvVar {
+						add(recv + "." + sel.Sel.Name)
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.KeyValueExpr:
+			// Keys of struct literals are field names.
+			if _, ok := x.Key.(*ast.Ident); !ok {
+				ast.Inspect(x.Key, visit)
+			}
+			ast.Inspect(x.Value, visit)
+			return false
+		case *ast.Field:
+			// Field, parameter and result names declare rather than refer.
+			ast.Inspect(x.Type, visit)
+			return false
+		case *ast.SelectorExpr:
+			// Only the left side can name a package-level declaration.
+			ast.Inspect(x.X, visit)
+			return false
+		case *ast.Ident:
+			add(x.Name)
+		}
+		return true
+	}
+	ast.Inspect(decl, visit)
+	sort.Strings(names)
+	return names
+}
+
+// extractSymbol looks up symbol in the packages of the given Go files and
+// returns source of the declaration and its same-package dependencies,
+// grouped per source file. Only the given files make up each package.
+func extractSymbol(paths []string, symbol string) ([]grabbed, error) {
+	type pkgKey struct{ dir, name string }
+	var keys []pkgKey
+	files := map[pkgKey][]string{}
+	for _, path := range paths {
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly)
+		if err != nil {
+			continue
+		}
+		key := pkgKey{dir: filepath.Dir(path), name: file.Name.Name}
+		if files[key] == nil {
+			keys = append(keys, key)
+		}
+		files[key] = append(files[key], path)
+	}
+
+	var result []grabbed
+	for _, key := range keys {
+		pkg, err := parseGoPackage(files[key], key.name)
+		if err != nil {
+			return nil, err
+		}
+		decls := pkg.resolve(symbol)
+		if len(decls) == 0 {
+			continue
+		}
+
+		byFile := map[string]*bytes.Buffer{}
+		var order []string
+		for _, d := range decls {
+			buf, ok := byFile[d.path]
+			if !ok {
+				buf = &bytes.Buffer{}
+				fmt.Fprintf(buf, "package %s\n", d.file.Name.Name)
+				byFile[d.path] = buf
+				order = append(order, d.path)
+			}
+			buf.WriteString("\n")
+			if err := goPrinter.Fprint(buf, pkg.fset, &printer.CommentedNode{Node: d.node, Comments: d.file.Comments}); err != nil {
+				return nil, err
+			}
+			buf.WriteString("\n")
+		}
+		for _, path := range order {
+			result = append(result, grabbed{Path: path, Content: byFile[path].String()})
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("symbol %q not found", symbol)
+	}
+	return result, nil
+}
@@ -0,0 +1,323 @@
+package grab
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const outlineSrc = `// Package shapes draws shapes.
+package shapes
+
+import "math"
+
+// Shape is anything with an area.
+type Shape interface {
+	Area() float64
+}
+
+// Circle is a round shape.
+type Circle struct {
+	R float64
+}
+
+// Area returns the area of c.
+func (c Circle) Area() float64 {
+	return math.Pi * c.R * c.R
+}
+
+const unit = 1
+
+var scale = 2
+
+// Total sums the areas of shapes.
+func Total(shapes []Shape) float64 {
+	sum := 0.0
+	for _, s := range shapes {
+		sum += s.Area()
+	}
+	return sum
+}
+`
+
+func TestOutlineGo(t *testing.T) {
+	t.Parallel()
+
+	out, err := outlineGo("shapes.go", []byte(outlineSrc))
+	if err != nil {
+		t.Fatalf("outlineGo failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		wanted bool
+	}{
+		{"package doc", "// Package shapes draws shapes.\npackage shapes\n", true},
+		{"type doc", "// Shape is anything with an area.\ntype Shape interface", true},
+		{"struct fields", "\tR float64\n", true},
+		{"method doc and signature", "// Area returns the area of c.\nfunc (c Circle) Area() float64\n", true},
+		{"function signature", "func Total(shapes []Shape) float64\n", true},
+		{"method body", "math.Pi", false},
+		{"function body", "sum += s.Area()", false},
+		{"imports", "import", false},
+		{"constants", "unit", false},
+		{"variables", "scale", false},
+	}
+	for _, tt := range tests {
+		if got := strings.Contains(out, tt.text); got != tt.wanted {
+			t.Errorf("%s: contains %q = %v, want %v\n%s", tt.name, tt.text, got, tt.wanted, out)
+		}
+	}
+}
+
+func TestOutlineGo_InvalidSource(t *testing.T) {
+	t.Parallel()
+
+	if _, err := outlineGo("bad.go", []byte("package\nfunc {")); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+const symbolSrcA = `package shapes
+
+// Circle is a round shape.
+type Circle struct {
+	R float64
+}
+
+// Area returns the area of c.
+func (c Circle) Area() float64 {
+	return pi * c.square()
+}
+
+func (c Circle) square() float64 { return c.R * c.R }
+
+// Perimeter is not used by Area.
+func (c Circle) Perimeter() float64 { return 2 * pi * c.R }
+`
+
+const symbolSrcB = `package shapes
+
+const pi = 3.14159
+
+// Describe names a circle.
+func Describe(c Circle) string { return label }
+
+var label = "circle"
+
+func unrelated() {}
+`
+
+func writeSymbolPackage(t *testing.T) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for name, src := range map[string]string{
+		"a.go":      symbolSrcA,
+		"b.go":      symbolSrcB,
+		"notes.txt": "Describe",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+func TestExtractSymbol(t *testing.T) {
+	t.Parallel()
+
+	paths := writeSymbolPackage(t)
+	tests := []struct {
+		symbol  string
+		want    []string
+		notWant []string
+	}{
+		{
+			symbol:  "Circle.Area",
+			want:    []string{"// Area returns the area of c.", "func (c Circle) square()", "type Circle struct", "const pi = 3.14159"},
+			notWant: []string{"Perimeter", "Describe", "unrelated"},
+		},
+		{
+			symbol:  "Describe",
+			want:    []string{"// Describe names a circle.", "type Circle struct", `var label = "circle"`},
+			notWant: []string{"func (c Circle) Area", "unrelated"},
+		},
+		{
+			symbol:  "pi",
+			want:    []string{"const pi"},
+			notWant: []string{"Circle", "label"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := extractSymbol(paths, tt.symbol)
+		if err != nil {
+			t.Fatalf("%s: extractSymbol failed: %v", tt.symbol, err)
+		}
+		var all strings.Builder
+		for _, g := range got {
+			if !strings.HasPrefix(g.Content, "package shapes\n") {
+				t.Errorf("%s: %s does not start with its package clause:\n%s", tt.symbol, g.Path, g.Content)
+			}
+			all.WriteString(g.Content)
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(all.String(), w) {
+				t.Errorf("%s: expected %q in\n%s", tt.symbol, w, all.String())
+			}
+		}
+		for _, w := range tt.notWant {
+			if strings.Contains(all.String(), w) {
+				t.Errorf("%s: did not expect %q in\n%s", tt.symbol, w, all.String())
+			}
+		}
+	}
+}
+
+func TestExtractSymbol_GroupsByFile(t *testing.T) {
+	t.Parallel()
+
+	got, err := extractSymbol(writeSymbolPackage(t), "Circle.Area")
+	if err != nil {
+		t.Fatalf("extractSymbol failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected declarations from 2 files, got %d", len(got))
+	}
+	if filepath.Base(got[0].Path) != "a.go" || filepath.Base(got[1].Path) != "b.go" {
+		t.Fatalf("expected a.go then b.go, got %s and %s", got[0].Path, got[1].Path)
+	}
+}
+
+func TestExtractSymbol_Unknown(t *testing.T) {
+	t.Parallel()
+
+	for _, symbol := range []string{"Square", "Circle.Volume", "Area"} {
+		if _, err := extractSymbol(writeSymbolPackage(t), symbol); err == nil {
+			t.Errorf("%s: expected error for unknown symbol", symbol)
+		}
+	}
+}
+
+func TestResolve_FollowsReferencesTransitively(t *testing.T) {
+	t.Parallel()
+
+	pkg, err := parseGoPackage(writeSymbolPackage(t), "shapes")
+	if err != nil {
+		t.Fatalf("parseGoPackage failed: %v", err)
+	}
+	var names []string
+	for _, d := range pkg.resolve("Circle.Area") {
+		names = append(names, d.name)
+	}
+	want := []string{"Circle.Area", "Circle", "Circle.square", "pi"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+	if pkg.resolve("missing") != nil {
+		t.Fatal("expected nil for unknown symbol")
+	}
+}
+
+func TestReferencedNames_SkipsFieldNamesAndLiteralKeys(t *testing.T) {
+	t.Parallel()
+
+	src := `package p
+
+type Config struct {
+	Width int
+	Label string
+}
+
+const Width = 10
+
+var Label = "x"
+
+func New(Width int) Config {
+	return Config{Width: Width, Label: fmt(Label)}
+}
+
+func fmt(s string) string { return s }
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.go")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := parseGoPackage([]string{path}, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, name := range referencedNames(pkg.decls["Config"].node) {
+		got[name] = true
+	}
+	if got["Width"] || got["Label"] {
+		t.Errorf("struct field names counted as references: %v", got)
+	}
+	got = map[string]bool{}
+	for _, name := range referencedNames(pkg.decls["New"].node) {
+		got[name] = true
+	}
+	// Width is only a parameter and a literal key; Label is a real reference.
+	if !got["Config"] || !got["Label"] || !got["fmt"] {
+		t.Errorf("missing references: %v", got)
+	}
+	if len(pkg.resolve("Config")) != 1 {
+		t.Errorf("Config pulled in more than itself")
+	}
+}
+
+func TestGoSources(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":         "package p\n",
+		"a_test.go":    "package p\n",
+		"b_test.go":    "package p\n",
+		"ignored.go":   "//go:build ignore\n\npackage p\n",
+		"notes.txt":    "p",
+		"other_xyz.go": "package p\n",
+	}
+	var paths []string
+	for name, src := range files {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	got := map[string]bool{}
+	for _, p := range goSources(paths, []string{dir, filepath.Join(dir, "b_test.go")}) {
+		got[filepath.Base(p)] = true
+	}
+	want := map[string]bool{"a.go": true, "b_test.go": true, "other_xyz.go": true}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for name := range want {
+		if !got[name] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestExtractSymbol_OnlyGivenFiles(t *testing.T) {
+	t.Parallel()
+
+	paths := writeSymbolPackage(t)
+	var withoutB []string
+	for _, p := range paths {
+		if filepath.Base(p) != "b.go" {
+			withoutB = append(withoutB, p)
+		}
+	}
+	if _, err := extractSymbol(withoutB, "Describe"); err == nil {
+		t.Fatal("expected Describe from an excluded file to be unknown")
+	}
+}
@@ -2,6 +2,7 @@ package grab
 
 import (
 	"fmt"
+	"handytools/pkg/match"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,114 +13,107 @@ type Config struct {
 	ListOnly        bool
 	ExcludePatterns []string
 	ExtraExclusions bool
+	Outline         bool
+	Symbol          string
+	IncludeBinary   bool
 }
 
-func RunWorker(config Config) {
-	inclusionList := expandWildcards(config.Inputs)
-	exclusionList := expandWildcards(config.ExcludePatterns)
-	exclusionMap := make(map[string]struct{})
-	for _, e := range exclusionList {
-		exclusionMap[e] = struct{}{}
-	}
+type grabbed struct {
+	Path    string
+	Content string
+	Binary  bool
+	Size    int64
+	MIME    string
+}
 
-	var filteredPaths []string
-	for _, incl := range inclusionList {
-		skip := false
-		for excl := range exclusionMap {
-			if strings.HasPrefix(incl, excl+"/") || strings.HasPrefix(incl, excl) || strings.Contains(incl, "/"+excl+"/") {
-				skip = true
-				break
-			}
-		}
-		if !skip {
-			filteredPaths = append(filteredPaths, incl)
+func RunWorker(config Config) {
+	filteredPaths := match.Expand(config.Inputs, match.New(config.ExcludePatterns))
+	if config.Symbol != "" {
+		entries, err := extractSymbol(goSources(filteredPaths, config.Inputs), config.Symbol)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "grab: %v\n", err)
+			return
 		}
+		printGrabbed(entries, config.ListOnly)
+		return
 	}
-	grabFiles(filteredPaths, config.ListOnly)
+	printGrabbed(grabFiles(filteredPaths, config), config.ListOnly)
 }
 
-func expandWildcards(paths []string) []string {
-	var expanded []string
-
-	for _, path := range paths {
-		path = filepath.ToSlash(filepath.Clean(path))
+func grabFiles(paths []string, config Config) []grabbed {
+	var entries []grabbed
+	for _, file := range paths {
+		fc, err := readContent(file)
+		if err != nil {
+			continue
+		}
 
-		if strings.Contains(path, "...") {
-			parts := strings.Split(path, "...")
-			baseDir := filepath.Clean(parts[0])
-			if baseDir == "" {
-				baseDir = "."
-			}
-			suffix := ""
-			if len(parts) > 1 {
-				suffix = parts[1]
+		if fc.Binary {
+			if config.IncludeBinary {
+				entries = append(entries, grabbed{Path: file, Binary: true, Size: fc.Size, MIME: fc.MIME})
 			}
+			continue
+		}
 
-			_ = filepath.Walk(baseDir, func(fp string, fi os.FileInfo, err error) error {
-				if err != nil {
-					return nil
-				}
-				if fi.IsDir() {
-					return nil
-				}
-				if strings.HasSuffix(filepath.ToSlash(fp), suffix) {
-					expanded = append(expanded, filepath.ToSlash(fp))
-				}
-				return nil
-			})
-		} else if strings.Contains(path, "*") {
-			files, _ := filepath.Glob(path)
-			for _, f := range files {
-				expanded = append(expanded, filepath.ToSlash(f))
+		text := fc.Text
+		if config.Outline && filepath.Ext(file) == ".go" {
+			if text, err = outlineGo(file, []byte(fc.Text)); err != nil {
+				fmt.Fprintf(os.Stderr, "grab: cannot outline %s: %v\n", file, err)
+				text = fc.Text
 			}
-		} else {
-			expanded = append(expanded, filepath.ToSlash(path))
 		}
+		entries = append(entries, grabbed{Path: file, Content: text, Size: fc.Size, MIME: fc.MIME})
 	}
-
-	return expanded
+	return entries
 }
 
-func grabFiles(paths []string, listOnly bool) {
-	var totalLines int
-	fileLineCounts := make(map[string]int)
-
-	for _, file := range paths {
-		content, err := os.ReadFile(file)
-		if err != nil {
-			continue
-		}
+func printGrabbed(entries []grabbed, listOnly bool) {
+	var totalLines, textFiles int
+	lineCounts := make([]int, len(entries))
 
-		if !isText(content) {
+	for i, e := range entries {
+		if e.Binary {
+			if !listOnly {
+				fmt.Println(e.Path)
+				fmt.Printf("[binary %s, %s]\n", formatSize(e.Size), e.MIME)
+				fmt.Println()
+			}
 			continue
 		}
 
-		lines := strings.Count(string(content), "\n") + 1
+		lines := strings.Count(e.Content, "\n") + 1
 		totalLines += lines
-		fileLineCounts[file] = lines
+		lineCounts[i] = lines
+		textFiles++
 
 		if !listOnly {
-			fmt.Println(file)
-			fmt.Println(string(content))
+			fmt.Println(e.Path)
+			fmt.Println(e.Content)
 			fmt.Println()
 		}
 	}
 
-	fmt.Printf("\nProvided (%d files %d lines):\n", len(fileLineCounts), totalLines)
-	for file, count := range fileLineCounts {
-		fmt.Printf("%s (%d)\n", file, count)
+	fmt.Printf("\nProvided (%d files %d lines):\n", textFiles, totalLines)
+	for i, e := range entries {
+		if e.Binary {
+			fmt.Printf("%s (binary %s, %s)\n", e.Path, formatSize(e.Size), e.MIME)
+			continue
+		}
+		fmt.Printf("%s (%d)\n", e.Path, lineCounts[i])
 	}
-	fmt.Printf("\nTotal (%d files %d lines):\n", len(fileLineCounts), totalLines)
+	if binaries := len(entries) - textFiles; binaries > 0 {
+		fmt.Printf("\nTotal (%d files %d lines, %d binary):\n", textFiles, totalLines, binaries)
+		return
+	}
+	fmt.Printf("\nTotal (%d files %d lines):\n", textFiles, totalLines)
 }
 
-func isText(data []byte) bool {
-	for _, b := range data {
-		if b == 0 {
-			return false // null byte → likely binary
-		}
-		if b < 0x09 {
-			return false
-		}
+func formatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.2f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
 	}
-	return true
+	return fmt.Sprintf("%d B", size)
 }
@@ -3,51 +3,219 @@ package assemble
 import (
 	"fmt"
 	"handytools/pkg/common"
-	"image"
-
-	"github.com/disintegration/imaging"
+	"handytools/pkg/label"
+	"handytools/pkg/layout"
+	"handytools/pkg/pdf"
+	"image/color"
+	"strings"
 )
 
 var logger = common.GetLogger()
 
+// Options control the canvas produced by AssembleImages.
+type Options struct {
+	Width        int                // Page width
+	Height       int                // Maximum page height
+	Margin       int                // Outer margin on every side of each page
+	Spacing      int                // Space between tiles
+	HeaderHeight int                // Space reserved at the top of each page
+	Background   color.NRGBA        // Canvas colour, alpha 0 for transparent PNG
+	Engine       layout.Engine      // Layout engine, justified rows when empty
+	Columns      int                // Column count for masonry layouts
+	PerPage      int                // Images per page for the mosaic layout
+	Mode         layout.Mode        // Packing mode, layout.ModeRows uses RowHeight as is
+	Breaking     layout.RowBreaking // Row breaking: layout.BreakGreedy (default) or layout.BreakOptimal
+	RowHeight    int                // Target row height for layout.ModeRows
+	MinRowHeight int                // Lower bound when shrinking rows to fit one page
+	MaxRowHeight int                // Upper bound for row height (0 = no limit)
+	FillLastPage bool               // Use taller rows on the last page to fill it
+	Format       string             // Output format: "jpg" or "png"
+
+	Caption         CaptionKind       // Text drawn with each image, none when empty
+	CaptionPosition CaptionPosition   // Below each image (default) or over its bottom edge; always over for mosaic
+	Captions        map[string]string // Caption text by path for CaptionText
+	Title           string            // Drawn in the header, which grows to fit; {page}, {pages} and {group} are replaced
+	Groups          map[string]string // Group name by path; each group starts a new page and is titled "{group}" without a Title
+	Footer          string            // Drawn at the bottom of each page; {page} and {pages} are replaced
+	FontSize        int               // Caption and footer size in pixels, the title is half as large again
+
+	PDF string // Also write all pages into this PDF file
+	DPI int    // Print resolution that sets the PDF page size
+}
+
+// DefaultOptions returns a 1080x1920 white portrait layout.
+func DefaultOptions() Options {
+	return Options{
+		Width:        maxWidth,
+		Height:       maxHeight,
+		Spacing:      DefaultSpace,
+		Background:   color.NRGBA{255, 255, 255, 255},
+		RowHeight:    defaultRowH,
+		MinRowHeight: minRowH,
+		Engine:       layout.EngineJustified,
+		Columns:      defaultCols,
+		PerPage:      defaultPerPage,
+		Mode:         layout.ModeFit,
+		Format:       "jpg",
+		FontSize:     defaultFontSize,
+		DPI:          defaultDPI,
+	}
+}
+
 func AssembleImagesWithMax(paths []string, outputPrefix string, fitOnePage bool) error {
-	logger.Infof("Assemble images: %d", len(paths))
-	var images []image.Image
-	for _, path := range paths {
-		img, err := imaging.Open(path)
-		if err != nil {
-			logger.WithError(err).Warn("Skipping image: ", path)
-			continue
-		}
-		images = append(images, img)
-		if len(images)%5 == 0 {
-			logger.Infof("Assembled images: %d", len(images))
-		}
+	opts := DefaultOptions()
+	if !fitOnePage {
+		opts.Mode = layout.ModeRows
+	}
+	return AssembleImages(paths, outputPrefix, opts)
+}
+
+// AssembleImages lays out paths and writes the pages to outputPrefix.format,
+// numbered when there are several, and to opts.PDF if set. An empty
+// outputPrefix writes only the PDF.
+func AssembleImages(paths []string, outputPrefix string, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
 	}
-	if len(images) == 0 {
+	if outputPrefix == "" && opts.PDF == "" {
+		return fmt.Errorf("no output file or PDF given")
+	}
+
+	logger.Infof("Assemble images: %d", len(paths))
+	sizes, names := probeImages(paths)
+	if len(sizes) == 0 {
 		return fmt.Errorf("no valid images to assemble")
 	}
+	names, sizes = groupOrder(names, sizes, opts.Groups)
 
-	canvas, pageBreaks, err := buildContinuousCanvas(images, fitOnePage)
-	if err != nil {
-		return fmt.Errorf("failed to build canvas: %w", err)
+	var captions []string
+	if opts.Caption != CaptionNone {
+		captions = captionTexts(names, opts)
 	}
 
-	out := fmt.Sprintf("%s.jpg", outputPrefix)
-	for i, y := range pageBreaks {
-		var top int
-		if i > 0 {
-			top = pageBreaks[i-1]
+	var doc *pdf.File
+	if opts.PDF != "" {
+		var err error
+		if doc, err = pdf.Create(opts.PDF); err != nil {
+			return fmt.Errorf("failed to create PDF: %w", err)
 		}
-		cropped := imaging.Crop(canvas, image.Rect(0, top, maxWidth, y-DefaultSpace))
-		if len(pageBreaks) > 1 {
-			out = fmt.Sprintf("%s_%02d.jpg", outputPrefix, i+1)
+		defer doc.Close()
+	}
+
+	open := fileSource(names)
+	pages, titles := groupPages(names, sizes, opts)
+	drawn := 0
+	for i, page := range pages {
+		for _, pos := range page.Images {
+			if pos.Crop > 0.005 {
+				logger.Infof("Cropped %.0f%%: %s", pos.Crop*100, names[pos.Index])
+			}
 		}
-		if err := imaging.Save(cropped, out); err != nil {
+		pageOpts := opts
+		pageOpts.Title = titles[i]
+		canvas, err := renderPage(open, page, pageOpts, captions, i+1, len(pages))
+		if err != nil {
+			return fmt.Errorf("failed to draw page %d: %w", i+1, err)
+		}
+		drawn += len(page.Images)
+		logger.Infof("Assembled images: %d/%d", drawn, len(sizes))
+
+		out := ""
+		if outputPrefix != "" {
+			out = fmt.Sprintf("%s.%s", outputPrefix, opts.Format)
+			if len(pages) > 1 {
+				out = fmt.Sprintf("%s_%02d.%s", outputPrefix, i+1, opts.Format)
+			}
+		}
+		if err := pdf.SavePage(canvas, out, doc, opts.DPI, jpegQuality); err != nil {
 			return fmt.Errorf("failed to save page %d: %w", i+1, err)
 		}
-		logger.Infof("Saved page: %s", out)
+		if out != "" {
+			logger.Infof("Saved page: %s", out)
+		}
+	}
+
+	if doc != nil {
+		if err := doc.Close(); err != nil {
+			return fmt.Errorf("failed to write PDF: %w", err)
+		}
+		logger.Infof("Saved PDF: %s", opts.PDF)
 	}
+	return nil
+}
 
+func (o *Options) validate() error {
+	o.Format = strings.ToLower(strings.TrimPrefix(o.Format, "."))
+	switch o.Format {
+	case "":
+		o.Format = "jpg"
+	case "jpg", "jpeg", "png":
+	default:
+		return fmt.Errorf("unsupported output format %q (use jpg or png)", o.Format)
+	}
+	if o.Background.A < 255 && o.Format != "png" {
+		logger.Warn("Transparent background requires PNG, writing PNG output")
+		o.Format = "png"
+	}
+	if o.DPI <= 0 {
+		o.DPI = defaultDPI
+	}
+	if o.FontSize <= 0 {
+		o.FontSize = defaultFontSize
+	}
+	switch {
+	case o.Engine == layout.EngineMosaic:
+		// Mosaic cells fill the page, leaving no room below them.
+		o.CaptionPosition = CaptionOver
+	case o.CaptionPosition == "":
+		o.CaptionPosition = CaptionBelow
+	}
+	if o.Title == "" && len(o.Groups) > 0 {
+		o.Title = "{group}"
+	}
+	if o.Title != "" {
+		o.HeaderHeight = max(o.HeaderHeight, label.LineHeight(o.titleSize()))
+	}
+	if o.Width <= 2*o.Margin {
+		return fmt.Errorf("width %d leaves no room inside margin %d", o.Width, o.Margin)
+	}
+	if o.Height <= 2*o.Margin+o.HeaderHeight+o.footerHeight()+o.captionHeight() {
+		return fmt.Errorf("height %d leaves no room inside margin %d, header %d, footer and captions", o.Height, o.Margin, o.HeaderHeight)
+	}
+	if o.Columns <= 0 {
+		o.Columns = defaultCols
+	}
+	if o.PerPage <= 0 {
+		o.PerPage = defaultPerPage
+	}
+	if o.RowHeight <= 0 {
+		return fmt.Errorf("row height must be positive, got %d", o.RowHeight)
+	}
+	if o.MinRowHeight <= 0 || o.MinRowHeight > o.RowHeight {
+		o.MinRowHeight = min(minRowH, o.RowHeight)
+	}
+	if o.MaxRowHeight > 0 && o.MaxRowHeight < o.RowHeight {
+		return fmt.Errorf("max row height %d is below row height %d", o.MaxRowHeight, o.RowHeight)
+	}
 	return nil
 }
+
+func (o Options) titleSize() int {
+	return o.FontSize * 3 / 2
+}
+
+// captionHeight is the band reserved below every image, zero when captions
+// are drawn over the images or not at all.
+func (o Options) captionHeight() int {
+	if o.Caption == CaptionNone || o.CaptionPosition == CaptionOver || o.Engine == layout.EngineMosaic {
+		return 0
+	}
+	return label.LineHeight(o.FontSize)
+}
+
+func (o Options) footerHeight() int {
+	if o.Footer == "" {
+		return 0
+	}
+	return label.LineHeight(o.FontSize)
+}
@@ -1,6 +1,7 @@
 package assemble_test
 
 import (
+	"bytes"
 	"image/color"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"github.com/disintegration/imaging"
 
 	. "handytools/pkg/assemble"
+	"handytools/pkg/layout"
 )
 
 func createTempImageFile(t *testing.T, dir string, name string, w, h int) string {
@@ -83,3 +85,103 @@ func TestAssembleImagesWithMax_SkipsBadImage_StillSucceeds(t *testing.T) {
 		t.Fatalf("expected at least one output image, got none")
 	}
 }
+
+func TestAssembleImages_TruncatedImageLeavesBlankCell(t *testing.T) {
+	tmp := t.TempDir()
+	good := createTempImageFile(t, tmp, "good.jpg", 500, 350)
+
+	// A JPEG cut off after its header passes probing but fails to decode.
+	full := createTempImageFile(t, tmp, "full.jpg", 500, 350)
+	data, err := os.ReadFile(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(tmp, "truncated.jpg")
+	if err := os.WriteFile(truncated, data[:len(data)/3], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imaging.Open(truncated); err == nil {
+		t.Fatal("expected truncated JPEG to fail decoding")
+	}
+
+	outPrefix := filepath.Join(tmp, "out")
+	opts := DefaultOptions()
+	opts.PDF = filepath.Join(tmp, "out.pdf")
+	if err := AssembleImages([]string{good, truncated}, outPrefix, opts); err != nil {
+		t.Fatalf("AssembleImages failed with a truncated image: %v", err)
+	}
+	if _, err := os.Stat(outPrefix + ".jpg"); err != nil {
+		t.Fatalf("expected page to be written: %v", err)
+	}
+	if info, err := os.Stat(opts.PDF); err != nil || info.Size() == 0 {
+		t.Fatalf("expected PDF to be finished, got %v", err)
+	}
+}
+
+func TestAssembleImages_TransparentBackgroundWritesPNG(t *testing.T) {
+	tmp := t.TempDir()
+	img1 := createTempImageFile(t, tmp, "t1.jpg", 400, 300)
+	img2 := createTempImageFile(t, tmp, "t2.jpg", 300, 400)
+
+	opts := DefaultOptions()
+	opts.Margin = 20
+	opts.Background = color.NRGBA{}
+	outPrefix := filepath.Join(tmp, "transparent")
+
+	if err := AssembleImages([]string{img1, img2}, outPrefix, opts); err != nil {
+		t.Fatalf("AssembleImages failed: %v", err)
+	}
+
+	out, err := imaging.Open(outPrefix + ".png")
+	if err != nil {
+		t.Fatalf("expected PNG output: %v", err)
+	}
+	if _, _, _, a := out.At(0, 0).RGBA(); a != 0 {
+		t.Fatalf("expected transparent margin pixel, got alpha %d", a)
+	}
+}
+
+func TestAssembleImages_RejectsMarginWiderThanPage(t *testing.T) {
+	tmp := t.TempDir()
+	img := createTempImageFile(t, tmp, "m.jpg", 400, 300)
+
+	opts := DefaultOptions()
+	opts.Margin = opts.Width / 2
+
+	if err := AssembleImages([]string{img}, filepath.Join(tmp, "bad"), opts); err == nil {
+		t.Fatalf("expected error for margin leaving no content area")
+	}
+}
+
+func TestAssembleImages_WritesPDFOnly(t *testing.T) {
+	tmp := t.TempDir()
+	var paths []string
+	for _, name := range []string{"p1.jpg", "p2.jpg", "p3.jpg"} {
+		paths = append(paths, createTempImageFile(t, tmp, name, 400, 300))
+	}
+
+	opts := DefaultOptions()
+	opts.Mode = layout.ModeLarge
+	opts.DPI = 72
+	opts.PDF = filepath.Join(tmp, "gallery.pdf")
+	if err := AssembleImages(paths, "", opts); err != nil {
+		t.Fatalf("AssembleImages failed: %v", err)
+	}
+
+	data, err := os.ReadFile(opts.PDF)
+	if err != nil {
+		t.Fatalf("expected PDF output: %v", err)
+	}
+	// One 810px row per image leaves room for two rows per page. At 72 dpi
+	// points equal pixels.
+	if !bytes.Contains(data, []byte("/Count 2")) || !bytes.Contains(data, []byte("/MediaBox [0 0 1080.00 ")) {
+		t.Fatalf("unexpected PDF page tree or size")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(tmp, "*_0*.jpg")); len(matches) != 0 {
+		t.Fatalf("expected no image pages, found %v", matches)
+	}
+
+	if err := AssembleImages(paths, "", DefaultOptions()); err == nil {
+		t.Fatalf("expected error without output prefix or PDF")
+	}
+}
@@ -0,0 +1,98 @@
+package assemble
+
+import (
+	"fmt"
+	"handytools/pkg/exif"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CaptionKind selects the text drawn with each image.
+type CaptionKind string
+
+const (
+	CaptionNone    CaptionKind = ""        // No captions
+	CaptionName    CaptionKind = "name"    // File name
+	CaptionCounter CaptionKind = "counter" // Position in the gallery, from 1
+	CaptionDate    CaptionKind = "date"    // EXIF date taken, else the file modification time
+	CaptionText    CaptionKind = "text"    // Options.Captions, e.g. from a path<TAB>caption list
+)
+
+func ParseCaption(s string) (CaptionKind, error) {
+	k := CaptionKind(strings.ToLower(strings.TrimSpace(s)))
+	switch k {
+	case "none":
+		return CaptionNone, nil
+	case CaptionNone, CaptionName, CaptionCounter, CaptionDate, CaptionText:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown caption %q (use none, name, counter, date or text)", s)
+}
+
+// CaptionPosition places captions in a band below each image or over its
+// bottom edge.
+type CaptionPosition string
+
+const (
+	CaptionBelow CaptionPosition = "below"
+	CaptionOver  CaptionPosition = "over"
+)
+
+func ParseCaptionPosition(s string) (CaptionPosition, error) {
+	p := CaptionPosition(strings.ToLower(strings.TrimSpace(s)))
+	switch p {
+	case "":
+		return CaptionBelow, nil
+	case CaptionBelow, CaptionOver:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown caption position %q (use below or over)", s)
+}
+
+const captionDateLayout = "2006-01-02 15:04"
+
+// captionTexts returns the caption of every path, in order.
+func captionTexts(paths []string, opts Options) []string {
+	texts := make([]string, len(paths))
+	for i, path := range paths {
+		switch opts.Caption {
+		case CaptionName:
+			texts[i] = filepath.Base(path)
+		case CaptionCounter:
+			texts[i] = strconv.Itoa(i + 1)
+		case CaptionDate:
+			texts[i] = dateTaken(path)
+		case CaptionText:
+			texts[i] = opts.Captions[path]
+		}
+	}
+	return texts
+}
+
+func dateTaken(path string) string {
+	if t, ok := takenTime(path); ok {
+		return t.Format(captionDateLayout)
+	}
+	return ""
+}
+
+// takenTime is the EXIF date taken of path, else its modification time.
+func takenTime(path string) (time.Time, bool) {
+	if d, err := exif.ReadFile(path); err == nil {
+		if t, ok := d.DateTaken(); ok {
+			return t, true
+		}
+	}
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime(), true
+	}
+	return time.Time{}, false
+}
+
+// pageText expands the {page} and {pages} placeholders of a title or footer.
+func pageText(s string, page, pages int) string {
+	return strings.NewReplacer("{page}", strconv.Itoa(page), "{pages}", strconv.Itoa(pages)).Replace(s)
+}
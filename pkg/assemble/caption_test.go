@@ -0,0 +1,148 @@
+package assemble
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"handytools/pkg/layout"
+)
+
+func TestParseCaption(t *testing.T) {
+	t.Parallel()
+
+	if k, err := ParseCaption("none"); err != nil || k != CaptionNone {
+		t.Fatalf("expected no caption, got %q (%v)", k, err)
+	}
+	if k, err := ParseCaption("Date"); err != nil || k != CaptionDate {
+		t.Fatalf("expected date, got %q (%v)", k, err)
+	}
+	if _, err := ParseCaption("exif"); err == nil {
+		t.Fatalf("expected error for unknown caption")
+	}
+	if _, err := ParseCaptionPosition("under"); err == nil {
+		t.Fatalf("expected error for unknown caption position")
+	}
+}
+
+func TestCaptionTexts(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	a := filepath.Join(tmp, "a.jpg")
+	b := filepath.Join(tmp, "b.jpg")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte("not a jpeg"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stamp := time.Date(2021, 3, 4, 5, 6, 0, 0, time.Local)
+	if err := os.Chtimes(a, stamp, stamp); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{a, b}
+
+	tests := []struct {
+		kind CaptionKind
+		want []string
+	}{
+		{CaptionName, []string{"a.jpg", "b.jpg"}},
+		{CaptionCounter, []string{"1", "2"}},
+		{CaptionText, []string{"first", ""}},
+	}
+	for _, tt := range tests {
+		opts := Options{Caption: tt.kind, Captions: map[string]string{a: "first"}}
+		got := captionTexts(paths, opts)
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s caption %d = %q, want %q", tt.kind, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	// Without EXIF the date falls back to the modification time.
+	if got := captionTexts(paths, Options{Caption: CaptionDate})[0]; got != "2021-03-04 05:06" {
+		t.Errorf("date caption = %q", got)
+	}
+}
+
+func TestPageText(t *testing.T) {
+	t.Parallel()
+
+	if got := pageText("Page {page} of {pages}", 2, 5); got != "Page 2 of 5" {
+		t.Fatalf("unexpected page text %q", got)
+	}
+}
+
+func hasInk(img *image.NRGBA, r image.Rectangle) bool {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if c := img.NRGBAAt(x, y); c.R < 128 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestRenderPage_DrawsCaptionsTitleAndFooter(t *testing.T) {
+	t.Parallel()
+
+	opts := fitOptions(true)
+	opts.Caption = CaptionCounter
+	opts.Title = "Gallery"
+	opts.Footer = "Page {page} of {pages}"
+	if err := opts.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if opts.HeaderHeight == 0 {
+		t.Fatalf("expected the header to grow for the title")
+	}
+
+	// White images on a white page: any dark pixel is text.
+	images := genImages(4, 600, 400, color.NRGBA{255, 255, 255, 255})
+	open := func(i int) (image.Image, error) { return images[i], nil }
+	pages := layoutPages(sizesOf(images), opts)
+	captions := captionTexts([]string{"a", "b", "c", "d"}, opts)
+	canvas, err := renderPage(open, pages[0], opts, captions, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	captionH := opts.captionHeight()
+	for _, pos := range pages[0].Images {
+		tile := image.Rect(pos.X, pos.Y, pos.X+pos.Width, pos.Y+pos.Height)
+		if hasInk(canvas, tile) {
+			t.Fatalf("caption of image %d drawn over the image", pos.Index)
+		}
+		band := tile.Add(image.Pt(0, pos.Height))
+		band.Max.Y = band.Min.Y + captionH
+		if !hasInk(canvas, band) {
+			t.Fatalf("no caption drawn below image %d", pos.Index)
+		}
+	}
+	if !hasInk(canvas, image.Rect(0, 0, opts.Width, opts.HeaderHeight)) {
+		t.Fatalf("no title drawn")
+	}
+	bottom := pages[0].Height
+	if !hasInk(canvas, image.Rect(0, bottom-opts.footerHeight(), opts.Width, bottom)) {
+		t.Fatalf("no footer drawn")
+	}
+}
+
+func TestLayoutPages_MosaicDrawsCaptionsOver(t *testing.T) {
+	t.Parallel()
+
+	opts := fitOptions(true)
+	opts.Engine = layout.EngineMosaic
+	opts.Caption = CaptionName
+	if err := opts.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if opts.CaptionPosition != CaptionOver || opts.captionHeight() != 0 {
+		t.Fatalf("expected mosaic captions over the images")
+	}
+}
@@ -0,0 +1,58 @@
+package assemble
+
+import (
+	"handytools/pkg/layout"
+	"image"
+	"strings"
+)
+
+// groupOrder moves the images of each group together, groups in the order
+// they first appear and images within a group in their original order.
+// Paths without a group form a group named "".
+func groupOrder(paths []string, sizes []image.Point, groups map[string]string) ([]string, []image.Point) {
+	if len(groups) == 0 {
+		return paths, sizes
+	}
+	var order []string
+	members := map[string][]int{}
+	for i, p := range paths {
+		g := groups[p]
+		if _, ok := members[g]; !ok {
+			order = append(order, g)
+		}
+		members[g] = append(members[g], i)
+	}
+	outPaths := make([]string, 0, len(paths))
+	outSizes := make([]image.Point, 0, len(sizes))
+	for _, g := range order {
+		for _, i := range members[g] {
+			outPaths = append(outPaths, paths[i])
+			outSizes = append(outSizes, sizes[i])
+		}
+	}
+	return outPaths, outSizes
+}
+
+// groupPages lays out every run of images of the same group on pages of its
+// own and returns each page's title with {group} filled in. paths must be in
+// groupOrder.
+func groupPages(paths []string, sizes []image.Point, opts Options) ([]layout.Page, []string) {
+	var pages []layout.Page
+	var titles []string
+	for start := 0; start < len(paths); {
+		g := opts.Groups[paths[start]]
+		end := start + 1
+		for end < len(paths) && opts.Groups[paths[end]] == g {
+			end++
+		}
+		for _, page := range layoutPages(sizes[start:end], opts) {
+			for i := range page.Images {
+				page.Images[i].Index += start
+			}
+			pages = append(pages, page)
+			titles = append(titles, strings.ReplaceAll(opts.Title, "{group}", g))
+		}
+		start = end
+	}
+	return pages, titles
+}
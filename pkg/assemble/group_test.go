@@ -0,0 +1,42 @@
+package assemble
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestGroupOrder(t *testing.T) {
+	paths := []string{"a", "b", "c", "d", "e"}
+	sizes := []image.Point{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}}
+	groups := map[string]string{"a": "x", "b": "y", "c": "x", "e": "y"}
+
+	gotPaths, gotSizes := groupOrder(paths, sizes, groups)
+	if want := []string{"a", "c", "b", "e", "d"}; !reflect.DeepEqual(gotPaths, want) {
+		t.Errorf("paths = %v, want %v", gotPaths, want)
+	}
+	if gotSizes[1] != (image.Point{3, 3}) || gotSizes[4] != (image.Point{4, 4}) {
+		t.Errorf("sizes did not follow their paths: %v", gotSizes)
+	}
+}
+
+func TestGroupPages_StartsPageForEachGroup(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Groups = map[string]string{"a": "Kitchen", "b": "Kitchen", "c": "Garden"}
+	if err := opts.validate(); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{"a", "b", "c"}
+	sizes := []image.Point{{400, 300}, {400, 300}, {300, 400}}
+
+	pages, titles := groupPages(paths, sizes, opts)
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, want one per group", len(pages))
+	}
+	if !reflect.DeepEqual(titles, []string{"Kitchen", "Garden"}) {
+		t.Errorf("titles = %q", titles)
+	}
+	if len(pages[1].Images) != 1 || pages[1].Images[0].Index != 2 {
+		t.Errorf("second page = %+v, want image 2 alone", pages[1].Images)
+	}
+}
@@ -1,61 +1,133 @@
 package assemble
 
 import (
+	"handytools/pkg/label"
 	"handytools/pkg/layout"
+	"handytools/pkg/smartcrop"
 	"image"
 	"image/color"
+	"image/draw"
 
 	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
 )
 
 const (
-	maxWidth     = 1080
-	maxHeight    = 1920
-	defaultRowH  = 320
-	DefaultSpace = 10
+	maxWidth       = 1080
+	maxHeight      = 1920
+	defaultRowH    = 320
+	minRowH        = 100
+	defaultCols    = 3
+	defaultPerPage = 5
+	DefaultSpace   = 10
+
+	defaultFontSize = 16
+	defaultDPI      = 150
+	jpegQuality     = 95
 )
 
-func buildContinuousCanvas(images []image.Image, fitOnePage bool) (*image.NRGBA, []int, error) {
+func layoutPages(sizes []image.Point, opts Options) []layout.Page {
 	var infos []layout.ImageInfo
-	for _, img := range images {
-		b := img.Bounds()
-		aspect := float64(b.Dx()) / float64(b.Dy())
+	for _, size := range sizes {
+		aspect := float64(size.X) / float64(size.Y)
 		infos = append(infos, layout.ImageInfo{Aspect: aspect})
 	}
 
-	rowHeight := defaultRowH
-	var positions []layout.PlacedImage
-	var canvasHeight int
-	var pageBreaks []int
-
-	for {
-		cfg := layout.Config{
-			MaxWidth:       maxWidth,
-			TargetHeight:   rowHeight,
-			Spacing:        DefaultSpace,
-			Tolerance:      0.25,
-			MinRowItems:    2,
-			MinAspectTotal: 0,
-		}
-
-		positions, canvasHeight, pageBreaks = layout.JustifyWithPageSplits(infos, cfg, maxHeight)
+	cfg := layout.Config{
+		MaxWidth:       opts.Width,
+		TargetHeight:   opts.RowHeight,
+		Spacing:        opts.Spacing,
+		Tolerance:      0.25,
+		MinRowItems:    2,
+		MinAspectTotal: 0,
+		MaxRowHeight:   opts.MaxRowHeight,
+		MinRowHeight:   opts.MinRowHeight,
+		Margin:         opts.Margin,
+		HeaderHeight:   opts.HeaderHeight,
+		FooterHeight:   opts.footerHeight(),
+		CaptionHeight:  opts.captionHeight(),
+		FillLastPage:   opts.FillLastPage,
+		Breaking:       opts.Breaking,
+	}
+	return newLayout(cfg, opts).Arrange(infos, opts.Height)
+}
 
-		if !fitOnePage || len(pageBreaks) <= 1 || rowHeight <= 100 {
-			break
+func newLayout(cfg layout.Config, opts Options) layout.Layout {
+	switch opts.Engine {
+	case layout.EngineMosaic:
+		return layout.Mosaic{Config: cfg, PerPage: opts.PerPage}
+	case layout.EngineMasonry, layout.EngineMixed:
+		return layout.Masonry{
+			Config:        cfg,
+			Columns:       opts.Columns,
+			SpanLandscape: opts.Engine == layout.EngineMixed,
+			Mode:          opts.Mode,
 		}
+	}
+	return layout.Justified{Config: cfg, Mode: opts.Mode}
+}
 
-		rowHeight -= 20
+// renderPage draws page number of pages, decoding each source just before it
+// is pasted and dropping it afterwards. Cells that crop their image (mosaic)
+// are filled with a smart crop, everything else is resized to the cell.
+// captions holds the caption of every image index, nil for none. Images
+// whose header was readable but that fail to decode, such as truncated
+// downloads, are logged and leave their cell blank.
+func renderPage(open imageSource, page layout.Page, opts Options, captions []string, number, pages int) (*image.NRGBA, error) {
+	canvas := imaging.New(opts.Width, page.Height, opts.Background)
+	ink := label.Ink(opts.Background)
+
+	var face font.Face
+	if captions != nil || opts.Footer != "" {
+		face = label.NewFace(opts.FontSize)
+		defer face.Close()
 	}
+	captionH := opts.captionHeight()
 
-	canvas := imaging.New(maxWidth, canvasHeight, color.NRGBA{255, 255, 255, 255})
-	for _, pos := range positions {
-		if pos.Index >= len(images) {
+	for _, pos := range page.Images {
+		src, err := open(pos.Index)
+		if err != nil {
+			logger.WithError(err).Warnf("Leaving cell blank for image %d", pos.Index+1)
 			continue
 		}
-		img := images[pos.Index]
-		resized := imaging.Resize(img, pos.Width, pos.Height, imaging.Lanczos)
-		canvas = imaging.Paste(canvas, resized, image.Pt(pos.X, pos.Y))
+		var tile *image.NRGBA
+		if pos.Crop > 0 {
+			tile = smartcrop.Fill(src, pos.Width, pos.Height)
+		} else {
+			tile = imaging.Resize(src, pos.Width, pos.Height, imaging.Lanczos)
+		}
+		pasteInto(canvas, tile, pos.X, pos.Y)
+
+		if captions == nil || captions[pos.Index] == "" {
+			continue
+		}
+		if captionH > 0 {
+			band := image.Rect(pos.X, pos.Y+pos.Height, pos.X+pos.Width, pos.Y+pos.Height+captionH)
+			label.Draw(canvas, band, captions[pos.Index], face, ink)
+		} else {
+			bandH := min(label.LineHeight(opts.FontSize), pos.Height)
+			band := image.Rect(pos.X, pos.Y+pos.Height-bandH, pos.X+pos.Width, pos.Y+pos.Height)
+			draw.Draw(canvas, band, image.NewUniform(color.NRGBA{0, 0, 0, 140}), image.Point{}, draw.Over)
+			label.Draw(canvas, band, captions[pos.Index], face, color.White)
+		}
 	}
 
-	return canvas, pageBreaks, nil
+	if opts.Title != "" {
+		title := label.NewFace(opts.titleSize())
+		defer title.Close()
+		header := image.Rect(opts.Margin, opts.Margin, opts.Width-opts.Margin, opts.Margin+opts.HeaderHeight)
+		label.Draw(canvas, header, pageText(opts.Title, number, pages), title, ink)
+	}
+	if opts.Footer != "" {
+		bottom := page.Height - opts.Margin
+		footer := image.Rect(opts.Margin, bottom-opts.footerHeight(), opts.Width-opts.Margin, bottom)
+		label.Draw(canvas, footer, pageText(opts.Footer, number, pages), face, ink)
+	}
+	return canvas, nil
+}
+
+// pasteInto copies tile onto canvas in place; imaging.Paste would allocate a
+// second page-sized canvas for every tile.
+func pasteInto(canvas, tile *image.NRGBA, x, y int) {
+	draw.Draw(canvas, tile.Bounds().Add(image.Pt(x, y)), tile, tile.Bounds().Min, draw.Src)
 }
@@ -5,6 +5,8 @@ import (
 	"image/color"
 	"testing"
 
+	"handytools/pkg/layout"
+
 	"github.com/disintegration/imaging"
 )
 
@@ -16,66 +18,83 @@ func genImages(n, w, h int, c color.NRGBA) []image.Image {
 	return images
 }
 
-func TestBuildContinuousCanvas_SinglePage_WhenFitOnePageTrue(t *testing.T) {
+func sizesOf(images []image.Image) []image.Point {
+	sizes := make([]image.Point, len(images))
+	for i, img := range images {
+		sizes[i] = img.Bounds().Size()
+	}
+	return sizes
+}
+
+func buildPages(t *testing.T, images []image.Image, opts Options) []*image.NRGBA {
+	t.Helper()
+	open := func(i int) (image.Image, error) { return images[i], nil }
+	var pages []*image.NRGBA
+	layouts := layoutPages(sizesOf(images), opts)
+	for i, page := range layouts {
+		canvas, err := renderPage(open, page, opts, nil, i+1, len(layouts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, canvas)
+	}
+	return pages
+}
+
+func fitOptions(fitOnePage bool) Options {
+	opts := DefaultOptions()
+	if !fitOnePage {
+		opts.Mode = layout.ModeRows
+	}
+	return opts
+}
+
+func TestBuildPages_SinglePage_WhenFitOnePageTrue(t *testing.T) {
 	t.Parallel()
 
 	// Many images that would normally overflow multiple pages at defaultRowH.
 	images := genImages(24, 1000, 1000, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
 
-	canvas, pageBreaks, err := buildContinuousCanvas(images, true)
-	if err != nil {
-		t.Fatalf("buildContinuousCanvas returned error: %v", err)
-	}
-
-	if canvas == nil {
-		t.Fatalf("expected non-nil canvas")
-	}
+	pages := buildPages(t, images, fitOptions(true))
 
 	// When fitOnePage=true, rowHeight should be reduced until it fits one page (or min row height).
-	if len(pageBreaks) > 1 {
-		t.Fatalf("expected <= 1 page when fitOnePage=true, got %d", len(pageBreaks))
+	if len(pages) != 1 {
+		t.Fatalf("expected 1 page when fitOnePage=true, got %d", len(pages))
 	}
 
-	if gotW := canvas.Bounds().Dx(); gotW != maxWidth {
-		t.Fatalf("expected canvas width %d, got %d", maxWidth, gotW)
+	if gotW := pages[0].Bounds().Dx(); gotW != maxWidth {
+		t.Fatalf("expected page width %d, got %d", maxWidth, gotW)
 	}
 
-	if gotH := canvas.Bounds().Dy(); gotH <= 0 || gotH > maxHeight {
-		t.Fatalf("expected canvas height in (0, %d], got %d", maxHeight, gotH)
+	if gotH := pages[0].Bounds().Dy(); gotH <= 0 || gotH > maxHeight {
+		t.Fatalf("expected page height in (0, %d], got %d", maxHeight, gotH)
 	}
 }
 
-func TestBuildContinuousCanvas_MultiPage_WhenFitOnePageFalse(t *testing.T) {
+func TestBuildPages_MultiPage_WhenFitOnePageFalse(t *testing.T) {
 	t.Parallel()
 
 	// Force multiple pages by using a large number of images.
 	images := genImages(48, 1200, 800, color.NRGBA{R: 200, G: 50, B: 50, A: 255})
 
-	canvas, pageBreaks, err := buildContinuousCanvas(images, false)
-	if err != nil {
-		t.Fatalf("buildContinuousCanvas returned error: %v", err)
-	}
-
-	if canvas == nil {
-		t.Fatalf("expected non-nil canvas")
-	}
+	pages := buildPages(t, images, fitOptions(false))
 
 	// With fitOnePage=false, pagination is allowed; expect at least 2 pages for a large set.
-	if len(pageBreaks) < 2 {
-		t.Fatalf("expected multiple pages, got %d", len(pageBreaks))
-	}
-
-	if gotW := canvas.Bounds().Dx(); gotW != maxWidth {
-		t.Fatalf("expected canvas width %d, got %d", maxWidth, gotW)
+	if len(pages) < 2 {
+		t.Fatalf("expected multiple pages, got %d", len(pages))
 	}
 
-	if gotH := canvas.Bounds().Dy(); gotH <= maxHeight {
-		// Not strictly required to be > maxHeight, but with a large set it should be taller than a single page.
-		t.Fatalf("expected canvas height to exceed single-page height %d, got %d", maxHeight, gotH)
+	for i, page := range pages {
+		if gotW := page.Bounds().Dx(); gotW != maxWidth {
+			t.Fatalf("page %d: expected width %d, got %d", i+1, maxWidth, gotW)
+		}
+		if gotH := page.Bounds().Dy(); gotH <= 0 || gotH > maxHeight {
+			t.Fatalf("page %d: expected height in (0, %d], got %d", i+1, maxHeight, gotH)
+		}
 	}
 }
 
-func TestBuildContinuousCanvas_BasicPlacementDimensions(t *testing.T) {
+func TestBuildPages_BasicPlacementDimensions(t *testing.T) {
 	t.Parallel()
 
 	images := []image.Image{
@@ -84,24 +103,66 @@ func TestBuildContinuousCanvas_BasicPlacementDimensions(t *testing.T) {
 		imaging.New(1200, 800, color.NRGBA{R: 0, G: 0, B: 0, A: 255}),
 	}
 
-	canvas, pageBreaks, err := buildContinuousCanvas(images, false)
-	if err != nil {
-		t.Fatalf("buildContinuousCanvas returned error: %v", err)
+	pages := buildPages(t, images, fitOptions(false))
+
+	if len(pages) == 0 {
+		t.Fatalf("expected at least one page, got 0")
 	}
 
-	if canvas == nil {
-		t.Fatalf("expected non-nil canvas")
+	if pages[0].Bounds().Dx() != maxWidth {
+		t.Fatalf("expected page width %d, got %d", maxWidth, pages[0].Bounds().Dx())
 	}
 
-	if len(pageBreaks) == 0 {
-		t.Fatalf("expected at least one page break (end of first page), got 0")
+	if pages[0].Bounds().Dy() <= 0 {
+		t.Fatalf("expected positive page height, got %d", pages[0].Bounds().Dy())
 	}
+}
 
-	if canvas.Bounds().Dx() != maxWidth {
-		t.Fatalf("expected canvas width %d, got %d", maxWidth, canvas.Bounds().Dx())
+func TestLayoutPages_EveryImagePlacedOnce(t *testing.T) {
+	t.Parallel()
+
+	images := genImages(60, 900, 1200, color.NRGBA{A: 255})
+	images = append(images, genImages(30, 1600, 900, color.NRGBA{A: 255})...)
+
+	seen := make(map[int]int)
+	for _, page := range layoutPages(sizesOf(images), fitOptions(false)) {
+		for _, p := range page.Images {
+			seen[p.Index]++
+		}
+	}
+	for i := range images {
+		if seen[i] != 1 {
+			t.Fatalf("image %d placed %d times", i, seen[i])
+		}
 	}
+}
+
+func TestBuildPages_MosaicFillsExactCanvas(t *testing.T) {
+	t.Parallel()
 
-	if canvas.Bounds().Dy() <= 0 {
-		t.Fatalf("expected positive canvas height, got %d", canvas.Bounds().Dy())
+	images := genImages(4, 1200, 800, color.NRGBA{R: 255, A: 255})
+	images = append(images, genImages(3, 800, 1200, color.NRGBA{G: 255, A: 255})...)
+
+	opts := DefaultOptions()
+	opts.Engine = layout.EngineMosaic
+	opts.Height = 1350
+	opts.Spacing = 0
+	opts.PerPage = 4
+
+	pages := buildPages(t, images, opts)
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(pages))
+	}
+	for i, page := range pages {
+		if page.Bounds().Dx() != 1080 || page.Bounds().Dy() != 1350 {
+			t.Fatalf("page %d: expected 1080x1350, got %v", i+1, page.Bounds())
+		}
+		for y := 0; y < 1350; y += 7 {
+			for x := 0; x < 1080; x += 7 {
+				if c := page.NRGBAAt(x, y); c.R < 128 && c.G < 128 {
+					t.Fatalf("page %d: uncovered pixel at %d,%d: %v", i+1, x, y, c)
+				}
+			}
+		}
 	}
 }
@@ -0,0 +1,247 @@
+package assemble
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+)
+
+// SortKey orders the images of a gallery.
+type SortKey string
+
+const (
+	SortNone       SortKey = ""           // Keep the input order
+	SortName       SortKey = "name"       // File name, numbers in natural order
+	SortDate       SortKey = "date"       // File modification time
+	SortTaken      SortKey = "taken"      // EXIF date taken, else the modification time
+	SortAspect     SortKey = "aspect"     // Width/height ratio, tallest first
+	SortColour     SortKey = "colour"     // Hue of the average colour, greys last from dark to light
+	SortRandom     SortKey = "random"     // Shuffled with the seed
+	SortInterleave SortKey = "interleave" // Landscape and portrait images alternating, for even rows
+)
+
+func ParseSort(s string) (SortKey, error) {
+	k := SortKey(strings.ToLower(strings.TrimSpace(s)))
+	switch k {
+	case "none":
+		return SortNone, nil
+	case "color":
+		return SortColour, nil
+	case SortNone, SortName, SortDate, SortTaken, SortAspect, SortColour, SortRandom, SortInterleave:
+		return k, nil
+	}
+	return "", fmt.Errorf("invalid sort %q (use name, date, taken, aspect, colour, random or interleave)", s)
+}
+
+// GroupKind splits a gallery into groups that each start a new page.
+type GroupKind string
+
+const (
+	GroupNone        GroupKind = ""            // One group
+	GroupFolder      GroupKind = "folder"      // Directory the image is in
+	GroupDate        GroupKind = "date"        // Day taken, from EXIF or else the modification time
+	GroupOrientation GroupKind = "orientation" // Landscape, portrait or square
+)
+
+func ParseGroupBy(s string) (GroupKind, error) {
+	k := GroupKind(strings.ToLower(strings.TrimSpace(s)))
+	switch k {
+	case "none":
+		return GroupNone, nil
+	case GroupNone, GroupFolder, GroupDate, GroupOrientation:
+		return k, nil
+	}
+	return "", fmt.Errorf("invalid grouping %q (use folder, date or orientation)", s)
+}
+
+// SortImages returns paths ordered by key. Images that cannot be read sort
+// last in their input order. seed drives SortRandom; 0 picks one from the
+// clock, which is logged so the order can be repeated.
+func SortImages(paths []string, key SortKey, seed int64) []string {
+	out := append([]string(nil), paths...)
+	switch key {
+	case SortNone:
+	case SortName:
+		sort.SliceStable(out, func(i, j int) bool { return naturalLess(filepath.Base(out[i]), filepath.Base(out[j])) })
+	case SortDate:
+		sortByValue(out, func(path string) (float64, bool) {
+			info, err := os.Stat(path)
+			if err != nil {
+				return 0, false
+			}
+			return float64(info.ModTime().UnixNano()), true
+		})
+	case SortTaken:
+		sortByValue(out, func(path string) (float64, bool) {
+			t, ok := takenTime(path)
+			return float64(t.UnixNano()), ok
+		})
+	case SortAspect:
+		sortByValue(out, func(path string) (float64, bool) {
+			size, err := probeImage(path)
+			return float64(size.X) / float64(size.Y), err == nil
+		})
+	case SortColour:
+		sortByValue(out, colourRank)
+	case SortRandom:
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+			logger.Infof("Random order with --seed %d", seed)
+		}
+		rand.New(rand.NewSource(seed)).Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	case SortInterleave:
+		out = interleave(out)
+	}
+	return out
+}
+
+// sortByValue sorts paths by ascending value, keeping paths without one at
+// the end. Values are computed once per path.
+func sortByValue(paths []string, value func(string) (float64, bool)) {
+	type keyed struct {
+		path string
+		v    float64
+		ok   bool
+	}
+	items := make([]keyed, len(paths))
+	for i, p := range paths {
+		v, ok := value(p)
+		items[i] = keyed{p, v, ok}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].ok != items[j].ok {
+			return items[i].ok
+		}
+		return items[i].v < items[j].v
+	})
+	for i, it := range items {
+		paths[i] = it.path
+	}
+}
+
+// interleave alternates landscape and portrait images, each kind in input
+// order, so rows mix wide and tall tiles. Square images count as landscape.
+func interleave(paths []string) []string {
+	var wide, tall, unreadable []string
+	for _, p := range paths {
+		size, err := probeImage(p)
+		switch {
+		case err != nil:
+			unreadable = append(unreadable, p)
+		case size.Y > size.X:
+			tall = append(tall, p)
+		default:
+			wide = append(wide, p)
+		}
+	}
+	out := make([]string, 0, len(paths))
+	for i := 0; i < max(len(wide), len(tall)); i++ {
+		if i < len(wide) {
+			out = append(out, wide[i])
+		}
+		if i < len(tall) {
+			out = append(out, tall[i])
+		}
+	}
+	return append(out, unreadable...)
+}
+
+// colourRank places an image on a colour wheel by the hue of its average
+// colour. Greys, whose hue means little, follow all colours from dark to
+// light.
+func colourRank(path string) (float64, bool) {
+	img, err := imaging.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	// Resizing to one pixel averages the whole image.
+	c := imaging.Resize(img, 1, 1, imaging.Box).NRGBAAt(0, 0)
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	if hi-lo < 0.08 {
+		return 360 + (hi+lo)/2, true
+	}
+	var hue float64
+	switch hi {
+	case r:
+		hue = math.Mod((g-b)/(hi-lo)+6, 6)
+	case g:
+		hue = (b-r)/(hi-lo) + 2
+	default:
+		hue = (r-g)/(hi-lo) + 4
+	}
+	return hue * 60, true
+}
+
+// naturalLess compares names case-insensitively with digit runs compared as
+// numbers, so img_2 comes before img_10.
+func naturalLess(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+// GroupImages names the group of each path for Options.Groups.
+func GroupImages(paths []string, kind GroupKind) map[string]string {
+	if kind == GroupNone {
+		return nil
+	}
+	groups := make(map[string]string, len(paths))
+	for _, p := range paths {
+		switch kind {
+		case GroupFolder:
+			groups[p] = filepath.Base(filepath.Dir(p))
+		case GroupDate:
+			if t, ok := takenTime(p); ok {
+				groups[p] = t.Format("2006-01-02")
+			}
+		case GroupOrientation:
+			if size, err := probeImage(p); err == nil {
+				groups[p] = orientation(size.X, size.Y)
+			}
+		}
+	}
+	return groups
+}
+
+func orientation(w, h int) string {
+	switch {
+	case w > h:
+		return "Landscape"
+	case h > w:
+		return "Portrait"
+	}
+	return "Square"
+}
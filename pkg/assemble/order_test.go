@@ -0,0 +1,143 @@
+package assemble
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/disintegration/imaging"
+)
+
+func writeImage(t *testing.T, dir, name string, w, h int) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := imaging.Save(imaging.New(w, h, color.Black), p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func bases(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		out = append(out, filepath.Base(p))
+	}
+	return out
+}
+
+func TestParseSort(t *testing.T) {
+	for in, want := range map[string]SortKey{"": SortNone, "none": SortNone, "Color": SortColour, "taken": SortTaken} {
+		if got, err := ParseSort(in); err != nil || got != want {
+			t.Errorf("ParseSort(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseSort("size"); err == nil {
+		t.Error("unknown sort accepted")
+	}
+	if _, err := ParseGroupBy("camera"); err == nil {
+		t.Error("unknown grouping accepted")
+	}
+}
+
+func TestSortImages(t *testing.T) {
+	dir := t.TempDir()
+	wide := writeImage(t, dir, "img_10.jpg", 300, 100)
+	tall := writeImage(t, dir, "img_2.jpg", 100, 300)
+	square := writeImage(t, dir, "IMG_1.jpg", 200, 200)
+	paths := []string{wide, tall, square}
+
+	if got := bases(SortImages(paths, SortName, 0)); !reflect.DeepEqual(got, []string{"IMG_1.jpg", "img_2.jpg", "img_10.jpg"}) {
+		t.Errorf("by name = %v", got)
+	}
+	if got := bases(SortImages(paths, SortAspect, 0)); !reflect.DeepEqual(got, []string{"img_2.jpg", "IMG_1.jpg", "img_10.jpg"}) {
+		t.Errorf("by aspect = %v", got)
+	}
+
+	now := time.Now()
+	for i, p := range []string{square, wide, tall} {
+		mod := now.Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(p, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := bases(SortImages(paths, SortDate, 0)); !reflect.DeepEqual(got, []string{"IMG_1.jpg", "img_10.jpg", "img_2.jpg"}) {
+		t.Errorf("by date = %v", got)
+	}
+
+	a, b := SortImages(paths, SortRandom, 42), SortImages(paths, SortRandom, 42)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed gave %v and %v", bases(a), bases(b))
+	}
+	if !reflect.DeepEqual(paths, []string{wide, tall, square}) {
+		t.Error("SortImages changed its input")
+	}
+}
+
+func TestSortImages_UnreadableLast(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.jpg")
+	os.WriteFile(bad, []byte("not an image"), 0644)
+	good := writeImage(t, dir, "good.jpg", 10, 10)
+
+	if got := SortImages([]string{bad, good}, SortAspect, 0); got[0] != good {
+		t.Errorf("by aspect = %v, want the unreadable file last", bases(got))
+	}
+}
+
+func TestSortImages_Interleave(t *testing.T) {
+	dir := t.TempDir()
+	w1 := writeImage(t, dir, "w1.jpg", 300, 200)
+	w2 := writeImage(t, dir, "w2.jpg", 300, 200)
+	w3 := writeImage(t, dir, "w3.jpg", 300, 200)
+	t1 := writeImage(t, dir, "t1.jpg", 200, 300)
+
+	got := bases(SortImages([]string{w1, w2, w3, t1}, SortInterleave, 0))
+	if want := []string{"w1.jpg", "t1.jpg", "w2.jpg", "w3.jpg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("interleaved = %v, want %v", got, want)
+	}
+}
+
+func TestSortImages_Colour(t *testing.T) {
+	dir := t.TempDir()
+	save := func(name string, c color.NRGBA) string {
+		p := filepath.Join(dir, name)
+		if err := imaging.Save(imaging.New(8, 8, c), p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	grey := save("grey.png", color.NRGBA{128, 128, 128, 255})
+	blue := save("blue.png", color.NRGBA{20, 40, 220, 255})
+	red := save("red.png", color.NRGBA{220, 30, 20, 255})
+	green := save("green.png", color.NRGBA{30, 200, 40, 255})
+
+	got := bases(SortImages([]string{grey, blue, red, green}, SortColour, 0))
+	if want := []string{"red.png", "green.png", "blue.png", "grey.png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("by colour = %v, want %v", got, want)
+	}
+}
+
+func TestGroupImages(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "trip"), 0755)
+	wide := writeImage(t, dir, "trip/wide.jpg", 300, 200)
+	tall := writeImage(t, dir, "tall.jpg", 200, 300)
+
+	if got := GroupImages([]string{wide, tall}, GroupOrientation); got[wide] != "Landscape" || got[tall] != "Portrait" {
+		t.Errorf("by orientation = %v", got)
+	}
+	if got := GroupImages([]string{wide, tall}, GroupFolder); got[wide] != "trip" || got[tall] != filepath.Base(dir) {
+		t.Errorf("by folder = %v", got)
+	}
+	day := time.Date(2024, 5, 17, 12, 0, 0, 0, time.Local)
+	os.Chtimes(tall, day, day)
+	if got := GroupImages([]string{tall}, GroupDate); got[tall] != "2024-05-17" {
+		t.Errorf("by date = %v", got)
+	}
+	if GroupImages([]string{tall}, GroupNone) != nil {
+		t.Error("no grouping gave groups")
+	}
+}
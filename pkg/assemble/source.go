@@ -0,0 +1,51 @@
+package assemble
+
+import (
+	"fmt"
+	"handytools/pkg/exif"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// imageSource decodes the i-th input image. Pages call it only while they are
+// being drawn, so at most one full-size source is held in memory at a time.
+type imageSource func(i int) (image.Image, error)
+
+// probeImages reads only the headers of paths and returns the sizes and paths
+// of the images that can be decoded, skipping the rest.
+func probeImages(paths []string) ([]image.Point, []string) {
+	var sizes []image.Point
+	var names []string
+	for _, path := range paths {
+		size, err := probeImage(path)
+		if err != nil {
+			logger.WithError(err).Warn("Skipping image: ", path)
+			continue
+		}
+		sizes = append(sizes, size)
+		names = append(names, path)
+	}
+	return sizes, names
+}
+
+func probeImage(path string) (image.Point, error) {
+	size, err := exif.ImageSize(path)
+	if err != nil {
+		return image.Point{}, err
+	}
+	if size.X == 0 || size.Y == 0 {
+		return image.Point{}, fmt.Errorf("empty image %dx%d", size.X, size.Y)
+	}
+	return size, nil
+}
+
+func fileSource(paths []string) imageSource {
+	return func(i int) (image.Image, error) {
+		img, err := imaging.Open(paths[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", paths[i], err)
+		}
+		return img, nil
+	}
+}
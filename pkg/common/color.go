@@ -0,0 +1,37 @@
+package common
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+var namedColors = map[string]color.NRGBA{
+	"white":       {R: 255, G: 255, B: 255, A: 255},
+	"black":       {R: 0, G: 0, B: 0, A: 255},
+	"cream":       {R: 255, G: 253, B: 231, A: 255},
+	"ivory":       {R: 255, G: 255, B: 240, A: 255},
+	"transparent": {R: 0, G: 0, B: 0, A: 0},
+}
+
+// ParseColor accepts a named color (white, black, cream, ivory, transparent),
+// #RRGGBB or #RRGGBBAA.
+func ParseColor(s string) (color.NRGBA, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := namedColors[s]; ok {
+		return c, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("unknown color %q — use white/black/cream/ivory/transparent or #RRGGBB[AA]", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid hex color %q", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseColor_Named(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseColor(" White ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != (color.NRGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Fatalf("unexpected color: %v", got)
+	}
+}
+
+func TestParseColor_Transparent(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseColor("transparent")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.A != 0 {
+		t.Fatalf("expected zero alpha, got %v", got)
+	}
+}
+
+func TestParseColor_Hex(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseColor("#102030")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != (color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff}) {
+		t.Fatalf("unexpected color: %v", got)
+	}
+
+	got, err = ParseColor("#10203080")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != (color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0x80}) {
+		t.Fatalf("unexpected color: %v", got)
+	}
+}
+
+func TestParseColor_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"mauve", "#12345", "#zzzzzz"} {
+		if _, err := ParseColor(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
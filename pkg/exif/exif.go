@@ -0,0 +1,245 @@
+// Package exif reads the few EXIF fields the tools need from JPEG files. It
+// understands the TIFF structure of the APP1 segment but none of the maker
+// notes, thumbnails or other image formats. ImageSize combines the image
+// header with the orientation tag to give the size an image is shown at.
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Tag identifies an EXIF field.
+type Tag uint16
+
+const (
+	Make              Tag = 0x010f
+	Model             Tag = 0x0110
+	Orientation       Tag = 0x0112
+	DateTime          Tag = 0x0132
+	ExposureTime      Tag = 0x829a
+	FNumber           Tag = 0x829d
+	ISO               Tag = 0x8827
+	DateTimeOriginal  Tag = 0x9003
+	DateTimeDigitized Tag = 0x9004
+	FocalLength       Tag = 0x920a
+	LensModel         Tag = 0xa434
+
+	exifPointer Tag = 0x8769
+)
+
+// ErrNoExif is returned for files without an EXIF segment.
+var ErrNoExif = errors.New("no EXIF data")
+
+const dateLayout = "2006:01:02 15:04:05"
+
+// field is one decoded IFD entry. Text holds ASCII values, Nums holds SHORT
+// and LONG values, and Rats holds RATIONAL values as numerator/denominator.
+type field struct {
+	Text string
+	Nums []uint32
+	Rats [][2]uint32
+}
+
+// Data holds the fields of IFD0 and the EXIF sub-IFD.
+type Data struct {
+	fields map[Tag]field
+}
+
+// ReadFile reads the EXIF data of a JPEG file.
+func ReadFile(path string) (*Data, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// ImageSize returns the size of an image as displayed, with width and height
+// swapped for orientations 5-8, which imaging.AutoOrientation rotates by 90°.
+// Only the header is read; the format must be registered with package image.
+func ImageSize(path string) (image.Point, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return image.Point{}, err
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return image.Point{}, err
+	}
+	size := image.Pt(cfg.Width, cfg.Height)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return size, nil
+	}
+	if d, err := Read(f); err == nil {
+		if o, ok := d.Int(Orientation); ok && o >= 5 && o <= 8 {
+			size.X, size.Y = size.Y, size.X
+		}
+	}
+	return size, nil
+}
+
+// Read reads the EXIF data of a JPEG stream, stopping at the image data.
+func Read(r io.Reader) (*Data, error) {
+	payload, err := app1(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	return parseTIFF(payload)
+}
+
+// app1 returns the TIFF payload of the first EXIF APP1 segment.
+func app1(r *bufio.Reader) ([]byte, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xff, 0xd8} {
+		return nil, fmt.Errorf("not a JPEG file")
+	}
+	for {
+		var marker [2]byte
+		if _, err := io.ReadFull(r, marker[:]); err != nil {
+			return nil, ErrNoExif
+		}
+		if marker[0] != 0xff {
+			return nil, fmt.Errorf("corrupt JPEG marker %#x", marker[0])
+		}
+		// Start of scan or end of image: no metadata follows.
+		if marker[1] == 0xda || marker[1] == 0xd9 {
+			return nil, ErrNoExif
+		}
+		var size uint16
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil || size < 2 {
+			return nil, ErrNoExif
+		}
+		segment := make([]byte, size-2)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil, ErrNoExif
+		}
+		if marker[1] == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+	}
+}
+
+func parseTIFF(b []byte) (*Data, error) {
+	if len(b) < 8 {
+		return nil, ErrNoExif
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("bad TIFF byte order %q", b[:2])
+	}
+	if order.Uint16(b[2:]) != 42 {
+		return nil, fmt.Errorf("bad TIFF magic")
+	}
+
+	d := &Data{fields: map[Tag]field{}}
+	if err := d.readIFD(b, order, order.Uint32(b[4:])); err != nil {
+		return nil, err
+	}
+	if ptr, ok := d.fields[exifPointer]; ok && len(ptr.Nums) == 1 {
+		if err := d.readIFD(b, order, ptr.Nums[0]); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// readIFD decodes the entries of the IFD at offset. Unknown types are skipped.
+func (d *Data) readIFD(b []byte, order binary.ByteOrder, offset uint32) error {
+	if int64(offset)+2 > int64(len(b)) {
+		return fmt.Errorf("IFD offset %d out of range", offset)
+	}
+	count := int(order.Uint16(b[offset:]))
+	entries := b[offset+2:]
+	if len(entries) < count*12 {
+		return fmt.Errorf("truncated IFD")
+	}
+	for i := 0; i < count; i++ {
+		e := entries[i*12 : i*12+12]
+		tag := Tag(order.Uint16(e))
+		typ := order.Uint16(e[2:])
+		n := order.Uint32(e[4:])
+
+		size := map[uint16]uint32{2: 1, 3: 2, 4: 4, 5: 8}[typ]
+		if size == 0 || n > uint32(len(b)) {
+			continue
+		}
+		value := e[8:12]
+		if total := size * n; total > 4 {
+			start := order.Uint32(e[8:])
+			if int64(start)+int64(total) > int64(len(b)) {
+				continue
+			}
+			value = b[start : start+total]
+		}
+
+		var f field
+		switch typ {
+		case 2:
+			f.Text = strings.TrimRight(string(value[:n]), "\x00 ")
+		case 3:
+			for j := uint32(0); j < n; j++ {
+				f.Nums = append(f.Nums, uint32(order.Uint16(value[j*2:])))
+			}
+		case 4:
+			for j := uint32(0); j < n; j++ {
+				f.Nums = append(f.Nums, order.Uint32(value[j*4:]))
+			}
+		case 5:
+			for j := uint32(0); j < n; j++ {
+				f.Rats = append(f.Rats, [2]uint32{order.Uint32(value[j*8:]), order.Uint32(value[j*8+4:])})
+			}
+		}
+		d.fields[tag] = f
+	}
+	return nil
+}
+
+// String returns an ASCII field, or "" when it is missing.
+func (d *Data) String(tag Tag) string {
+	return d.fields[tag].Text
+}
+
+// Int returns the first SHORT or LONG value of a field.
+func (d *Data) Int(tag Tag) (int, bool) {
+	f, ok := d.fields[tag]
+	if !ok || len(f.Nums) == 0 {
+		return 0, false
+	}
+	return int(f.Nums[0]), true
+}
+
+// Rational returns the first RATIONAL value of a field.
+func (d *Data) Rational(tag Tag) (num, den uint32, ok bool) {
+	f, found := d.fields[tag]
+	if !found || len(f.Rats) == 0 || f.Rats[0][1] == 0 {
+		return 0, 0, false
+	}
+	return f.Rats[0][0], f.Rats[0][1], true
+}
+
+// DateTaken returns DateTimeOriginal, falling back to DateTimeDigitized and
+// DateTime. EXIF dates carry no time zone; they are returned in UTC.
+func (d *Data) DateTaken() (time.Time, bool) {
+	for _, tag := range []Tag{DateTimeOriginal, DateTimeDigitized, DateTime} {
+		if t, err := time.Parse(dateLayout, d.String(tag)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
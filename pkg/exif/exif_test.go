@@ -0,0 +1,138 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type entry struct {
+	tag   Tag
+	typ   uint16
+	count uint32
+	data  []byte // value bytes; stored inline when 4 bytes or fewer
+}
+
+// buildTIFF writes a big-endian TIFF block with IFD0 and an EXIF sub-IFD.
+func buildTIFF(ifd0, sub []entry) []byte {
+	order := binary.BigEndian
+	ifdSize := func(n int) int { return 2 + 12*n + 4 }
+
+	ifd0 = append(ifd0, entry{tag: exifPointer, typ: 4, count: 1})
+	ifd0Off := 8
+	subOff := ifd0Off + ifdSize(len(ifd0))
+	dataOff := subOff + ifdSize(len(sub))
+
+	var extra []byte
+	write := func(b []byte, entries []entry) []byte {
+		b = order.AppendUint16(b, uint16(len(entries)))
+		for _, e := range entries {
+			b = order.AppendUint16(b, uint16(e.tag))
+			b = order.AppendUint16(b, e.typ)
+			b = order.AppendUint32(b, e.count)
+			switch {
+			case e.tag == exifPointer:
+				b = order.AppendUint32(b, uint32(subOff))
+			case len(e.data) <= 4:
+				b = append(b, append(e.data, make([]byte, 4-len(e.data))...)...)
+			default:
+				b = order.AppendUint32(b, uint32(dataOff+len(extra)))
+				extra = append(extra, e.data...)
+			}
+		}
+		return order.AppendUint32(b, 0)
+	}
+
+	b := []byte("MM\x00\x2a")
+	b = order.AppendUint32(b, uint32(ifd0Off))
+	b = write(b, ifd0)
+	b = write(b, sub)
+	return append(b, extra...)
+}
+
+func ascii(tag Tag, s string) entry {
+	return entry{tag: tag, typ: 2, count: uint32(len(s) + 1), data: append([]byte(s), 0)}
+}
+
+// jpegWithExif encodes a w x h JPEG whose APP1 segment holds tiff.
+func jpegWithExif(t *testing.T, tiff []byte, w, h int) []byte {
+	t.Helper()
+	var img bytes.Buffer
+	if err := jpeg.Encode(&img, image.NewGray(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	var out bytes.Buffer
+	out.Write([]byte{0xff, 0xd8, 0xff, 0xe1})
+	binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(img.Bytes()[2:])
+	return out.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	rational := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 1), 250)
+	tiff := buildTIFF(
+		[]entry{ascii(Make, "Acme"), ascii(DateTime, "2020:01:01 00:00:00")},
+		[]entry{
+			ascii(DateTimeOriginal, "2019:07:14 18:30:05"),
+			{tag: ISO, typ: 3, count: 1, data: []byte{0x01, 0x90}},
+			{tag: ExposureTime, typ: 5, count: 1, data: rational},
+		},
+	)
+
+	d, err := Read(bytes.NewReader(jpegWithExif(t, tiff, 8, 8)))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if got := d.String(Make); got != "Acme" {
+		t.Errorf("Make = %q, want Acme", got)
+	}
+	if iso, ok := d.Int(ISO); !ok || iso != 400 {
+		t.Errorf("ISO = %d, %v, want 400", iso, ok)
+	}
+	if num, den, ok := d.Rational(ExposureTime); !ok || num != 1 || den != 250 {
+		t.Errorf("ExposureTime = %d/%d, %v, want 1/250", num, den, ok)
+	}
+	want := time.Date(2019, 7, 14, 18, 30, 5, 0, time.UTC)
+	if got, ok := d.DateTaken(); !ok || !got.Equal(want) {
+		t.Errorf("DateTaken = %v, %v, want %v", got, ok, want)
+	}
+}
+
+func TestRead_NoExif(t *testing.T) {
+	var img bytes.Buffer
+	if err := jpeg.Encode(&img, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(&img); err != ErrNoExif {
+		t.Fatalf("expected ErrNoExif, got %v", err)
+	}
+	if _, err := Read(bytes.NewReader([]byte("GIF89a"))); err == nil {
+		t.Fatalf("expected error for non-JPEG input")
+	}
+}
+
+func TestImageSize(t *testing.T) {
+	dir := t.TempDir()
+	for o := 1; o <= 8; o++ {
+		tiff := buildTIFF([]entry{{tag: Orientation, typ: 3, count: 1, data: []byte{0, byte(o)}}}, nil)
+		path := filepath.Join(dir, "oriented.jpg")
+		if err := os.WriteFile(path, jpegWithExif(t, tiff, 60, 40), 0644); err != nil {
+			t.Fatal(err)
+		}
+		want := image.Pt(60, 40)
+		if o >= 5 {
+			want = image.Pt(40, 60)
+		}
+		got, err := ImageSize(path)
+		if err != nil || got != want {
+			t.Errorf("orientation %d: got %v, %v; want %v", o, got, err, want)
+		}
+	}
+}
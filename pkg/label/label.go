@@ -0,0 +1,95 @@
+// Package label draws single lines of text onto images with an embedded
+// font, so output is identical on every machine.
+package label
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// The Go Regular font is compiled in, so text renders the same everywhere.
+var (
+	regular     *opentype.Font
+	regularOnce sync.Once
+)
+
+// NewFace returns the embedded font at size pixels. Faces keep a glyph cache
+// and are not safe for concurrent use; close them when done.
+func NewFace(size int) font.Face {
+	regularOnce.Do(func() {
+		var err error
+		if regular, err = opentype.Parse(goregular.TTF); err != nil {
+			panic(err)
+		}
+	})
+	face, err := opentype.NewFace(regular, &opentype.FaceOptions{Size: float64(size), DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		panic(err)
+	}
+	return face
+}
+
+// LineHeight is the height of one line of text at size, including padding
+// of a third of the size above and below.
+func LineHeight(size int) int {
+	face := NewFace(size)
+	defer face.Close()
+	m := face.Metrics()
+	return (m.Ascent + m.Descent).Ceil() + 2*(size/3)
+}
+
+// Fit shortens s with an ellipsis until it is at most width pixels wide.
+func Fit(face font.Face, s string, width int) string {
+	if font.MeasureString(face, s).Ceil() <= width {
+		return s
+	}
+	runes := []rune(s)
+	for n := len(runes) - 1; n > 0; n-- {
+		t := string(runes[:n]) + "…"
+		if font.MeasureString(face, t).Ceil() <= width {
+			return t
+		}
+	}
+	return ""
+}
+
+// Draw centres one line of text horizontally and vertically in r,
+// shortening it to fit.
+func Draw(dst draw.Image, r image.Rectangle, s string, face font.Face, c color.Color) {
+	s = Fit(face, s, r.Dx()-face.Metrics().Height.Ceil()/2)
+	if s == "" {
+		return
+	}
+	m := face.Metrics()
+	w := font.MeasureString(face, s)
+	textH := m.Ascent + m.Descent
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot: fixed.Point26_6{
+			X: fixed.I(r.Min.X) + (fixed.I(r.Dx())-w)/2,
+			Y: fixed.I(r.Min.Y) + (fixed.I(r.Dy())-textH)/2 + m.Ascent,
+		},
+	}
+	d.DrawString(s)
+}
+
+// Ink picks black or white, whichever contrasts more with bg.
+func Ink(bg color.NRGBA) color.NRGBA {
+	if bg.A < 128 {
+		return color.NRGBA{0, 0, 0, 255}
+	}
+	luma := 0.299*float64(bg.R) + 0.587*float64(bg.G) + 0.114*float64(bg.B)
+	if luma > 140 {
+		return color.NRGBA{0, 0, 0, 255}
+	}
+	return color.NRGBA{255, 255, 255, 255}
+}
@@ -0,0 +1,67 @@
+package label
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+)
+
+func TestFit(t *testing.T) {
+	t.Parallel()
+
+	face := NewFace(16)
+	defer face.Close()
+
+	if got := Fit(face, "short", 200); got != "short" {
+		t.Fatalf("expected text that fits to be unchanged, got %q", got)
+	}
+	long := strings.Repeat("wide ", 20)
+	got := Fit(face, long, 100)
+	if !strings.HasSuffix(got, "…") || font.MeasureString(face, got).Ceil() > 100 {
+		t.Fatalf("expected ellipsised text within 100px, got %q", got)
+	}
+	if got := Fit(face, long, 1); got != "" {
+		t.Fatalf("expected nothing to fit in 1px, got %q", got)
+	}
+}
+
+func TestDraw_StaysInsideRect(t *testing.T) {
+	t.Parallel()
+
+	face := NewFace(16)
+	defer face.Close()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 60))
+	r := image.Rect(20, 20, 180, 20+LineHeight(16))
+	Draw(img, r, "Contact sheet", face, color.Black)
+
+	inked := 0
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 200; x++ {
+			if img.NRGBAAt(x, y).A == 0 {
+				continue
+			}
+			if !image.Pt(x, y).In(r) {
+				t.Fatalf("pixel (%d,%d) drawn outside %v", x, y, r)
+			}
+			inked++
+		}
+	}
+	if inked == 0 {
+		t.Fatalf("expected text to be drawn")
+	}
+}
+
+func TestInk(t *testing.T) {
+	t.Parallel()
+
+	if c := Ink(color.NRGBA{255, 255, 240, 255}); c.R != 0 {
+		t.Fatalf("expected dark text on cream, got %v", c)
+	}
+	if c := Ink(color.NRGBA{0, 0, 0, 255}); c.R != 255 {
+		t.Fatalf("expected light text on black, got %v", c)
+	}
+}
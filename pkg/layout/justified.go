@@ -1,6 +1,10 @@
 package layout
 
-import "math"
+import (
+	"fmt"
+	"math"
+	"strings"
+)
 
 type ImageInfo struct {
 	Aspect float64 // width / height
@@ -11,77 +15,242 @@ type PlacedImage struct {
 	X, Y   int
 	Width  int
 	Height int
+	Crop   float64 // Share of the image cut away to fill the cell (mosaic only)
+}
+
+// Page holds the images placed on one output page. Coordinates are relative
+// to the page, and Height includes the margins and header.
+type Page struct {
+	Height int
+	Images []PlacedImage
 }
 
 type Config struct {
-	MaxWidth       int     // Total canvas width (e.g. 1080)
-	TargetHeight   int     // Desired row height
-	Spacing        int     // Space between tiles
-	Tolerance      float64 // Acceptable row height deviation (e.g. 0.25 = ±25%)
-	MinRowItems    int     // Minimum images per row before finalizing
-	MinAspectTotal float64 // Minimum total aspect ratio before forcing row
+	MaxWidth       int         // Total canvas width (e.g. 1080)
+	TargetHeight   int         // Desired row height
+	Spacing        int         // Space between tiles
+	Tolerance      float64     // Acceptable row height deviation (e.g. 0.25 = ±25%)
+	MinRowItems    int         // Minimum images per row before finalizing
+	MaxRowItems    int         // Maximum images per row (0 = no limit)
+	MinAspectTotal float64     // Minimum total aspect ratio before forcing row
+	MaxRowHeight   int         // Rows taller than this are shrunk and left-aligned (0 = no limit)
+	MinRowHeight   int         // Smallest row height tried by ModeFit
+	Margin         int         // Outer margin on every side of each page
+	HeaderHeight   int         // Space reserved at the top of each page, below the margin
+	FooterHeight   int         // Space reserved at the bottom of each page, above the margin
+	CaptionHeight  int         // Space below every image for its caption
+	FillLastPage   bool        // Re-justify the last page with taller rows to fill it
+	Breaking       RowBreaking // Row breaking algorithm, BreakGreedy when empty
+}
+
+// RowBreaking selects how images are split into rows.
+type RowBreaking string
+
+const (
+	BreakGreedy  RowBreaking = "greedy"  // Close each row as soon as it is acceptable (default)
+	BreakOptimal RowBreaking = "optimal" // Minimise deviation from TargetHeight over all rows
+)
+
+func ParseRowBreaking(s string) (RowBreaking, error) {
+	b := RowBreaking(strings.ToLower(strings.TrimSpace(s)))
+	switch b {
+	case BreakGreedy, BreakOptimal:
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown row breaking %q (use greedy or optimal)", s)
+}
+
+// contentWidth is the width available to a row inside the page margins.
+func (cfg Config) contentWidth() int {
+	return cfg.MaxWidth - 2*cfg.Margin
 }
 
-// JustifyWithPageSplits returns layout and vertical split points for paging
-func JustifyWithPageSplits(images []ImageInfo, cfg Config, maxPageHeight int) (placed []PlacedImage, canvasHeight int, pageBreaks []int) {
+// row is a run of consecutive images sharing one height.
+type row struct {
+	start, count int
+	height       float64
+	full         bool // spans the whole content width
+}
+
+// JustifyWithPageSplits arranges images in justified rows and distributes the
+// rows over pages no taller than maxPageHeight. Rows never cross a page break;
+// a row that is taller than a whole page on its own is scaled down to fit.
+func JustifyWithPageSplits(images []ImageInfo, cfg Config, maxPageHeight int) []Page {
+	if len(images) == 0 {
+		return nil
+	}
 	if cfg.MinRowItems < 1 {
 		cfg.MinRowItems = 1
 	}
-	if cfg.MinAspectTotal == 0 {
-		cfg.MinAspectTotal = float64(cfg.MaxWidth-cfg.Spacing*(cfg.MinRowItems-1)) / float64(cfg.TargetHeight)
+
+	pages := paginate(justifyRows(images, cfg), cfg, maxPageHeight)
+
+	if cfg.FillLastPage && len(pages) > 0 {
+		last := pages[len(pages)-1]
+		pages[len(pages)-1] = fillPage(images, last, cfg, maxPageHeight)
+	}
+
+	var result []Page
+	for _, rows := range pages {
+		result = append(result, placeRows(rows, images, cfg))
+	}
+	return result
+}
+
+func justifyRows(images []ImageInfo, cfg Config) []row {
+	if cfg.Breaking == BreakOptimal && len(images) <= maxOptimalImages {
+		return optimalRows(images, cfg)
+	}
+	return greedyRows(images, cfg)
+}
+
+// greedyRows closes a row as soon as its height is within tolerance of the
+// target or its aspect total is large enough.
+func greedyRows(images []ImageInfo, cfg Config) []row {
+	minAspectTotal := cfg.MinAspectTotal
+	if minAspectTotal == 0 {
+		minAspectTotal = float64(cfg.contentWidth()-cfg.Spacing*(cfg.MinRowItems-1)) / float64(cfg.TargetHeight)
 	}
 
 	var (
-		row         []ImageInfo
-		rowAspect   float64
-		y           = 0
-		startIdx    = 0
-		lastImageIx = len(images) - 1
+		rows      []row
+		rowAspect float64
+		start     = 0
+		last      = len(images) - 1
 	)
 
 	for i, img := range images {
-		row = append(row, img)
 		rowAspect += img.Aspect
+		count := i - start + 1
 
-		rowWidth := float64(cfg.MaxWidth - (len(row)-1)*cfg.Spacing)
+		rowWidth := float64(cfg.contentWidth() - (count-1)*cfg.Spacing)
 		rowHeight := rowWidth / rowAspect
 
 		validHeight := rowHeight >= float64(cfg.TargetHeight)*(1-cfg.Tolerance) &&
 			rowHeight <= float64(cfg.TargetHeight)*(1+cfg.Tolerance)
-		enoughItems := len(row) >= cfg.MinRowItems
-		enoughAspect := rowAspect >= cfg.MinAspectTotal
-		isLast := i == lastImageIx
-
-		if ((validHeight && enoughItems) || enoughAspect) || isLast {
-			scale := rowWidth / rowAspect
-			x := 0
-			rowTop := y
-			for j, r := range row {
-				w := int(math.Round(scale * r.Aspect))
-				h := int(math.Round(scale))
-				placed = append(placed, PlacedImage{
-					Index:  startIdx + j,
-					X:      x,
-					Y:      y,
-					Width:  w,
-					Height: h,
-				})
-				x += w + cfg.Spacing
-			}
-			y += int(math.Round(scale)) + cfg.Spacing
-			startIdx = i + 1
-			row = nil
+		enoughItems := count >= cfg.MinRowItems
+		enoughAspect := rowAspect >= minAspectTotal
+		rowFull := cfg.MaxRowItems > 0 && count >= cfg.MaxRowItems
+
+		if (validHeight && enoughItems) || enoughAspect || rowFull || i == last {
+			height := cfg.capHeight(rowHeight)
+			rows = append(rows, row{start: start, count: count, height: height, full: height == rowHeight})
+			start = i + 1
 			rowAspect = 0
+		}
+	}
+	return rows
+}
 
-			if y-rowTop > 0 && y > 0 && y%maxPageHeight <= int(scale) {
-				pageBreaks = append(pageBreaks, y)
-			}
+func (cfg Config) capHeight(h float64) float64 {
+	if cfg.MaxRowHeight > 0 && h > float64(cfg.MaxRowHeight) {
+		return float64(cfg.MaxRowHeight)
+	}
+	return h
+}
+
+// available is the height left for rows on a page.
+func (cfg Config) available(maxPageHeight int) int {
+	return maxPageHeight - 2*cfg.Margin - cfg.HeaderHeight - cfg.FooterHeight
+}
+
+// rowsHeight is the height of rows stacked with their captions and spacing.
+func (cfg Config) rowsHeight(rows []row) int {
+	h := 0
+	for i, r := range rows {
+		if i > 0 {
+			h += cfg.Spacing
+		}
+		h += int(math.Round(r.height)) + cfg.CaptionHeight
+	}
+	return h
+}
+
+func paginate(rows []row, cfg Config, maxPageHeight int) [][]row {
+	avail := cfg.available(maxPageHeight)
+
+	var (
+		pages [][]row
+		page  []row
+		used  int
+	)
+	for _, r := range rows {
+		if limit := float64(avail - cfg.CaptionHeight); limit < r.height {
+			r.height = limit
+			r.full = false
+		}
+		h := int(math.Round(r.height)) + cfg.CaptionHeight
+		if len(page) > 0 && used+cfg.Spacing+h > avail {
+			pages = append(pages, page)
+			page, used = nil, 0
 		}
+		if len(page) > 0 {
+			used += cfg.Spacing
+		}
+		page = append(page, r)
+		used += h
+	}
+	if len(page) > 0 {
+		pages = append(pages, page)
 	}
+	return pages
+}
+
+// fillPage re-justifies the images of a page with taller target row heights
+// and keeps whichever arrangement fills the most of the page. Optimal breaking
+// is used so the extra height is spread over all rows instead of ending in one
+// oversized last row. Row breaking is not monotonic in the target height, so
+// candidates are scanned rather than bisected.
+func fillPage(images []ImageInfo, page []row, cfg Config, maxPageHeight int) []row {
+	const step = 4
+	avail := cfg.available(maxPageHeight)
+	first := page[0].start
+	lastRow := page[len(page)-1]
+	subset := images[first : lastRow.start+lastRow.count]
 
-	if len(pageBreaks) == 0 || pageBreaks[len(pageBreaks)-1] != y {
-		pageBreaks = append(pageBreaks, y)
+	best, bestHeight := page, cfg.rowsHeight(page)
+	for target := cfg.TargetHeight + step; target <= avail; target += step {
+		trial := cfg
+		trial.TargetHeight = target
+		trial.MinAspectTotal = 0
+		trial.Breaking = BreakOptimal
+		rows := justifyRows(subset, trial)
+		if h := trial.rowsHeight(rows); h > bestHeight && h <= avail {
+			for i := range rows {
+				rows[i].start += first
+			}
+			best, bestHeight = rows, h
+		}
 	}
+	return best
+}
 
-	return placed, y, pageBreaks
+func placeRows(rows []row, images []ImageInfo, cfg Config) Page {
+	page := Page{}
+	y := cfg.Margin + cfg.HeaderHeight
+	for i, r := range rows {
+		if i > 0 {
+			y += cfg.Spacing
+		}
+		h := int(math.Round(r.height))
+		x := cfg.Margin
+		for j := 0; j < r.count; j++ {
+			w := int(math.Round(r.height * images[r.start+j].Aspect))
+			if r.full && j == r.count-1 {
+				// Absorb rounding so justified rows end exactly at the margin.
+				w = cfg.MaxWidth - cfg.Margin - x
+			}
+			page.Images = append(page.Images, PlacedImage{
+				Index:  r.start + j,
+				X:      x,
+				Y:      y,
+				Width:  w,
+				Height: h,
+			})
+			x += w + cfg.Spacing
+		}
+		y += h + cfg.CaptionHeight
+	}
+	page.Height = y + cfg.FooterHeight + cfg.Margin
+	return page
 }
@@ -0,0 +1,192 @@
+package layout
+
+import "testing"
+
+func uniformImages(n int, aspect float64) []ImageInfo {
+	images := make([]ImageInfo, n)
+	for i := range images {
+		images[i] = ImageInfo{Aspect: aspect}
+	}
+	return images
+}
+
+func mixedImages(n int) []ImageInfo {
+	aspects := []float64{0.67, 1.5, 0.8, 1.0, 1.78, 0.56, 1.33}
+	images := make([]ImageInfo, n)
+	for i := range images {
+		images[i] = ImageInfo{Aspect: aspects[i%len(aspects)]}
+	}
+	return images
+}
+
+func defaultConfig() Config {
+	return Config{
+		MaxWidth:     1080,
+		TargetHeight: 320,
+		Spacing:      10,
+		Tolerance:    0.25,
+		MinRowItems:  2,
+	}
+}
+
+func TestJustifyWithPageSplits_RespectsMarginsAndHeader(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultConfig()
+	cfg.TargetHeight = 300
+	cfg.Margin = 40
+	cfg.HeaderHeight = 100
+	pages := JustifyWithPageSplits(uniformImages(6, 1.5), cfg, 1920)
+
+	if len(pages) != 1 {
+		t.Fatalf("expected a single page, got %d", len(pages))
+	}
+	placed := pages[0].Images
+	if len(placed) != 6 {
+		t.Fatalf("expected 6 placements, got %d", len(placed))
+	}
+	for _, p := range placed {
+		if p.X < cfg.Margin || p.X+p.Width > cfg.MaxWidth-cfg.Margin {
+			t.Fatalf("image %d at x=%d w=%d leaves the margins", p.Index, p.X, p.Width)
+		}
+	}
+	if placed[0].Y != cfg.Margin+cfg.HeaderHeight {
+		t.Fatalf("expected first row at %d, got %d", cfg.Margin+cfg.HeaderHeight, placed[0].Y)
+	}
+	last := placed[len(placed)-1]
+	if want := last.Y + last.Height + cfg.Margin; pages[0].Height != want {
+		t.Fatalf("expected page height %d, got %d", want, pages[0].Height)
+	}
+}
+
+func TestJustifyWithPageSplits_ReservesCaptionsAndFooter(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultConfig()
+	cfg.CaptionHeight = 24
+	cfg.FooterHeight = 30
+	pages := JustifyWithPageSplits(mixedImages(60), cfg, 1920)
+	if len(pages) < 2 {
+		t.Fatalf("expected several pages, got %d", len(pages))
+	}
+
+	for i, page := range pages {
+		rows := map[int]int{} // y -> height
+		for _, p := range page.Images {
+			rows[p.Y] = p.Height
+		}
+		bottom := 0
+		for y, h := range rows {
+			// The caption band below a row must stay clear of the next row.
+			for other := range rows {
+				if other > y && other < y+h+cfg.CaptionHeight+cfg.Spacing {
+					t.Fatalf("page %d: row at %d overlaps caption of row at %d", i+1, other, y)
+				}
+			}
+			bottom = max(bottom, y+h+cfg.CaptionHeight)
+		}
+		if want := bottom + cfg.FooterHeight; page.Height != want {
+			t.Fatalf("page %d: expected height %d, got %d", i+1, want, page.Height)
+		}
+		if page.Height > 1920 {
+			t.Fatalf("page %d is %d tall", i+1, page.Height)
+		}
+	}
+}
+
+func TestJustifyWithPageSplits_MaxRowHeightCapsLastRow(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultConfig()
+	cfg.TargetHeight = 300
+	cfg.MaxRowHeight = 400
+	// A single landscape image would otherwise be scaled to full width.
+	pages := JustifyWithPageSplits(uniformImages(1, 1.5), cfg, 1920)
+	placed := pages[0].Images
+
+	if placed[0].Height != 400 {
+		t.Fatalf("expected height capped at 400, got %d", placed[0].Height)
+	}
+	if placed[0].Width != 600 {
+		t.Fatalf("expected width 600, got %d", placed[0].Width)
+	}
+}
+
+func TestJustifyWithPageSplits_NoRowCrossesPageBreak(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultConfig()
+	cfg.Margin = 30
+	cfg.HeaderHeight = 80
+	const pageHeight = 1920
+	pages := JustifyWithPageSplits(mixedImages(120), cfg, pageHeight)
+
+	if len(pages) < 2 {
+		t.Fatalf("expected several pages, got %d", len(pages))
+	}
+	next := 0
+	for i, page := range pages {
+		if page.Height > pageHeight {
+			t.Fatalf("page %d is %d tall, above %d", i+1, page.Height, pageHeight)
+		}
+		for _, p := range page.Images {
+			if p.Index != next {
+				t.Fatalf("page %d: expected image %d, got %d", i+1, next, p.Index)
+			}
+			next++
+			if p.Y < cfg.Margin+cfg.HeaderHeight || p.Y+p.Height > page.Height-cfg.Margin {
+				t.Fatalf("page %d: image %d spans %d..%d outside the content area", i+1, p.Index, p.Y, p.Y+p.Height)
+			}
+		}
+	}
+	if next != 120 {
+		t.Fatalf("expected 120 images placed, got %d", next)
+	}
+}
+
+func TestJustifyWithPageSplits_FullPagesLeaveLessThanARow(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultConfig()
+	const pageHeight = 1920
+	pages := JustifyWithPageSplits(uniformImages(40, 1.5), cfg, pageHeight)
+
+	for i, page := range pages[:len(pages)-1] {
+		rowHeight := page.Images[0].Height
+		if pageHeight-page.Height >= rowHeight+cfg.Spacing {
+			t.Fatalf("page %d ends at %d although another row of %d fits", i+1, page.Height, rowHeight)
+		}
+	}
+}
+
+func TestJustifyWithPageSplits_FillLastPage(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultConfig()
+	const pageHeight = 1920
+	plain := JustifyWithPageSplits(mixedImages(9), cfg, pageHeight)
+
+	cfg.FillLastPage = true
+	filled := JustifyWithPageSplits(mixedImages(9), cfg, pageHeight)
+
+	if len(plain) != 1 || len(filled) != 1 {
+		t.Fatalf("expected single pages, got %d and %d", len(plain), len(filled))
+	}
+	if filled[0].Height <= plain[0].Height {
+		t.Fatalf("expected filled page to be taller than %d, got %d", plain[0].Height, filled[0].Height)
+	}
+	if filled[0].Height > pageHeight {
+		t.Fatalf("filled page %d exceeds page height %d", filled[0].Height, pageHeight)
+	}
+	if len(filled[0].Images) != 9 {
+		t.Fatalf("expected all 9 images on the filled page, got %d", len(filled[0].Images))
+	}
+}
+
+func TestJustifyWithPageSplits_Empty(t *testing.T) {
+	t.Parallel()
+
+	if pages := JustifyWithPageSplits(nil, defaultConfig(), 1920); len(pages) != 0 {
+		t.Fatalf("expected no pages, got %d", len(pages))
+	}
+}
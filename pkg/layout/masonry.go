@@ -0,0 +1,149 @@
+package layout
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Layout arranges images on pages no taller than maxPageHeight.
+type Layout interface {
+	Arrange(images []ImageInfo, maxPageHeight int) []Page
+}
+
+// Engine names a Layout implementation.
+type Engine string
+
+const (
+	EngineJustified Engine = "justified" // Rows of equal height
+	EngineMasonry   Engine = "masonry"   // Columns of equal width
+	EngineMixed     Engine = "mixed"     // Masonry where landscape images span two columns
+	EngineMosaic    Engine = "mosaic"    // Exact-size pages cut into cropped cells
+)
+
+func ParseEngine(s string) (Engine, error) {
+	e := Engine(strings.ToLower(strings.TrimSpace(s)))
+	switch e {
+	case EngineJustified, EngineMasonry, EngineMixed, EngineMosaic:
+		return e, nil
+	}
+	return "", fmt.Errorf("unknown layout %q (use justified, masonry, mixed or mosaic)", s)
+}
+
+// Justified is the row-based Layout; see Arrange for the modes.
+type Justified struct {
+	Config Config
+	Mode   Mode
+}
+
+func (j Justified) Arrange(images []ImageInfo, maxPageHeight int) []Page {
+	return Arrange(images, j.Mode, j.Config, maxPageHeight)
+}
+
+// spanAspect is the aspect ratio from which an image counts as landscape.
+const spanAspect = 1.3
+
+// maxFitColumns bounds how many columns ModeFit tries.
+const maxFitColumns = 12
+
+// Masonry stacks images in fixed-width columns, always adding to the column
+// that is currently shortest. With SpanLandscape, landscape images take two
+// adjacent columns. A page ends when the next image does not fit below the
+// shortest column; ModeFit adds columns until everything fits one page.
+type Masonry struct {
+	Config        Config // MaxWidth, Spacing, Margin, header, footer and caption heights are used
+	Columns       int
+	SpanLandscape bool
+	Mode          Mode
+}
+
+func (m Masonry) Arrange(images []ImageInfo, maxPageHeight int) []Page {
+	if len(images) == 0 {
+		return nil
+	}
+	columns := max(m.Columns, 1)
+	if m.Mode != ModeFit {
+		return m.arrange(images, columns, maxPageHeight)
+	}
+	var pages []Page
+	for ; columns <= maxFitColumns; columns++ {
+		pages = m.arrange(images, columns, maxPageHeight)
+		if len(pages) <= 1 {
+			break
+		}
+	}
+	return pages
+}
+
+func (m Masonry) arrange(images []ImageInfo, columns, maxPageHeight int) []Page {
+	cfg := m.Config
+	colWidth := float64(cfg.contentWidth()-(columns-1)*cfg.Spacing) / float64(columns)
+	top := cfg.Margin + cfg.HeaderHeight
+	bottom := maxPageHeight - cfg.Margin - cfg.FooterHeight
+
+	var pages []Page
+	page := Page{}
+	heights := make([]int, columns)
+	reset := func() {
+		for c := range heights {
+			heights[c] = top
+		}
+	}
+	finish := func() {
+		end := top
+		for _, h := range heights {
+			end = max(end, h-cfg.Spacing)
+		}
+		page.Height = end + cfg.FooterHeight + cfg.Margin
+		pages = append(pages, page)
+		page = Page{}
+		reset()
+	}
+	reset()
+
+	for i, img := range images {
+		span := 1
+		if m.SpanLandscape && columns > 1 && img.Aspect >= spanAspect {
+			span = 2
+		}
+		width := colWidth*float64(span) + float64((span-1)*cfg.Spacing)
+		h := int(math.Round(width / img.Aspect))
+		w := int(math.Round(width))
+		if h > bottom-top-cfg.CaptionHeight {
+			// Taller than a page: shrink and centre in its column.
+			h = bottom - top - cfg.CaptionHeight
+			w = int(math.Round(float64(h) * img.Aspect))
+		}
+
+		col, y := shortest(heights, span)
+		if y+h+cfg.CaptionHeight > bottom && len(page.Images) > 0 {
+			finish()
+			col, y = shortest(heights, span)
+		}
+
+		x := cfg.Margin + int(math.Round(float64(col)*(colWidth+float64(cfg.Spacing))))
+		x += (int(math.Round(width)) - w) / 2
+		page.Images = append(page.Images, PlacedImage{Index: i, X: x, Y: y, Width: w, Height: h})
+		for c := col; c < col+span; c++ {
+			heights[c] = y + h + cfg.CaptionHeight + cfg.Spacing
+		}
+	}
+	finish()
+	return pages
+}
+
+// shortest returns the leftmost run of span columns whose tallest column is
+// the lowest, and the y where an image placed there starts.
+func shortest(heights []int, span int) (int, int) {
+	best, bestY := 0, math.MaxInt
+	for c := 0; c+span <= len(heights); c++ {
+		y := 0
+		for _, h := range heights[c : c+span] {
+			y = max(y, h)
+		}
+		if y < bestY {
+			best, bestY = c, y
+		}
+	}
+	return best, bestY
+}
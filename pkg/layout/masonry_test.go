@@ -0,0 +1,146 @@
+package layout
+
+import "testing"
+
+func masonryConfig() Config {
+	return Config{MaxWidth: 1080, Spacing: 10}
+}
+
+func overlaps(a, b PlacedImage) bool {
+	return a.X < b.X+b.Width && b.X < a.X+a.Width && a.Y < b.Y+b.Height && b.Y < a.Y+a.Height
+}
+
+func TestParseEngine(t *testing.T) {
+	t.Parallel()
+
+	if e, err := ParseEngine("Masonry"); err != nil || e != EngineMasonry {
+		t.Fatalf("expected masonry, got %q (%v)", e, err)
+	}
+	if _, err := ParseEngine("grid"); err == nil {
+		t.Fatalf("expected error for unknown layout")
+	}
+}
+
+func TestMasonry_AddsToShortestColumn(t *testing.T) {
+	t.Parallel()
+
+	m := Masonry{Config: masonryConfig(), Columns: 3}
+	// A tall image in the first column; the fourth image must go to column 2 or 3.
+	images := []ImageInfo{{0.5}, {1.5}, {1.5}, {1.5}}
+	pages := m.Arrange(images, 1920)
+
+	placed := pages[0].Images
+	colWidth := (1080 - 2*10) / 3
+	if placed[0].Width < colWidth-1 || placed[0].Width > colWidth+1 {
+		t.Fatalf("expected column width ~%d, got %d", colWidth, placed[0].Width)
+	}
+	if placed[3].X == placed[0].X {
+		t.Fatalf("expected fourth image beside the tall one, got x=%d", placed[3].X)
+	}
+	if placed[3].Y <= placed[1].Y {
+		t.Fatalf("expected fourth image stacked below a short one, got y=%d", placed[3].Y)
+	}
+}
+
+func TestMasonry_NoOverlapAndPagesWithinHeight(t *testing.T) {
+	t.Parallel()
+
+	cfg := masonryConfig()
+	cfg.Margin = 20
+	cfg.HeaderHeight = 60
+	for _, span := range []bool{false, true} {
+		m := Masonry{Config: cfg, Columns: 4, SpanLandscape: span}
+		pages := m.Arrange(mixedImages(90), 1920)
+		if len(pages) < 2 {
+			t.Fatalf("expected several pages, got %d", len(pages))
+		}
+		next := 0
+		for i, page := range pages {
+			if page.Height > 1920 {
+				t.Fatalf("page %d is %d tall", i+1, page.Height)
+			}
+			for j, a := range page.Images {
+				if a.Index != next {
+					t.Fatalf("expected image %d, got %d", next, a.Index)
+				}
+				next++
+				if a.X < cfg.Margin || a.X+a.Width > cfg.MaxWidth-cfg.Margin+1 {
+					t.Fatalf("page %d: image %d leaves the margins", i+1, a.Index)
+				}
+				if a.Y < cfg.Margin+cfg.HeaderHeight || a.Y+a.Height > page.Height-cfg.Margin {
+					t.Fatalf("page %d: image %d leaves the content area", i+1, a.Index)
+				}
+				for _, b := range page.Images[j+1:] {
+					if overlaps(a, b) {
+						t.Fatalf("page %d: images %d and %d overlap", i+1, a.Index, b.Index)
+					}
+				}
+			}
+		}
+		if next != 90 {
+			t.Fatalf("expected 90 images placed, got %d", next)
+		}
+	}
+}
+
+func TestMasonry_ReservesCaptions(t *testing.T) {
+	t.Parallel()
+
+	cfg := masonryConfig()
+	cfg.CaptionHeight = 24
+	cfg.FooterHeight = 30
+	pages := Masonry{Config: cfg, Columns: 3}.Arrange(mixedImages(40), 1920)
+
+	for i, page := range pages {
+		bottom := 0
+		for j, a := range page.Images {
+			band := a
+			band.Height += cfg.CaptionHeight
+			for _, b := range page.Images[j+1:] {
+				if overlaps(band, b) {
+					t.Fatalf("page %d: image %d overlaps the caption of image %d", i+1, b.Index, a.Index)
+				}
+			}
+			bottom = max(bottom, band.Y+band.Height)
+		}
+		if bottom+cfg.FooterHeight > page.Height || page.Height > 1920 {
+			t.Fatalf("page %d: captions end at %d, page is %d tall", i+1, bottom, page.Height)
+		}
+	}
+}
+
+func TestMasonry_MixedSpansLandscape(t *testing.T) {
+	t.Parallel()
+
+	m := Masonry{Config: masonryConfig(), Columns: 3, SpanLandscape: true}
+	pages := m.Arrange([]ImageInfo{{0.75}, {1.78}, {0.75}}, 1920)
+
+	portrait, landscape := pages[0].Images[0], pages[0].Images[1]
+	if landscape.Width < 2*portrait.Width {
+		t.Fatalf("expected landscape to span two columns, got width %d vs %d", landscape.Width, portrait.Width)
+	}
+}
+
+func TestMasonry_FitAddsColumns(t *testing.T) {
+	t.Parallel()
+
+	m := Masonry{Config: masonryConfig(), Columns: 2, Mode: ModeFit}
+	pages := m.Arrange(uniformImages(30, 0.75), 1920)
+	if len(pages) != 1 {
+		t.Fatalf("expected one page, got %d", len(pages))
+	}
+}
+
+func TestLayoutInterface(t *testing.T) {
+	t.Parallel()
+
+	layouts := []Layout{
+		Justified{Config: defaultConfig(), Mode: ModeCompact},
+		Masonry{Config: masonryConfig(), Columns: 3},
+	}
+	for _, l := range layouts {
+		if pages := l.Arrange(mixedImages(10), 1920); len(pages) == 0 {
+			t.Fatalf("%T returned no pages", l)
+		}
+	}
+}
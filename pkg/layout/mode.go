@@ -0,0 +1,95 @@
+package layout
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode selects how densely images are packed and how pages are used.
+type Mode string
+
+const (
+	ModeRows    Mode = "rows"    // Rows at TargetHeight, split into pages; also the zero Mode
+	ModeFit     Mode = "fit"     // Everything on one page, rows as tall as possible
+	ModeCompact Mode = "compact" // Up to 6 images per row
+	ModeMedium  Mode = "medium"  // Up to 3 images per row
+	ModeLarge   Mode = "large"   // One image per row
+)
+
+var modeMaxRowItems = map[Mode]int{
+	ModeCompact: 6,
+	ModeMedium:  3,
+	ModeLarge:   1,
+}
+
+// fitStep is how much the row height shrinks between ModeFit attempts.
+const fitStep = 4
+
+func ParseMode(s string) (Mode, error) {
+	m := Mode(strings.ToLower(strings.TrimSpace(s)))
+	switch m {
+	case ModeRows, ModeFit, ModeCompact, ModeMedium, ModeLarge:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown layout mode %q (use fit, rows, compact, medium or large)", s)
+}
+
+// Arrange lays out images in justified rows according to mode.
+//
+// ModeRows keeps rows at TargetHeight. ModeFit tries row heights from a full
+// page down to MinRowHeight and keeps the tallest that puts everything on one
+// page. The other modes cap the number of images per row, derive the target
+// row height from the average aspect ratio so a full row holds that many
+// images, and fill the last page when there is more than one.
+func Arrange(images []ImageInfo, mode Mode, cfg Config, maxPageHeight int) []Page {
+	if len(images) == 0 {
+		return nil
+	}
+
+	switch mode {
+	case ModeRows, "":
+		return JustifyWithPageSplits(images, cfg, maxPageHeight)
+	case ModeFit:
+		return fitOnePage(images, cfg, maxPageHeight)
+	}
+
+	n := modeMaxRowItems[mode]
+	cfg.MaxRowItems = n
+	cfg.MinRowItems = min(max(cfg.MinRowItems, 1), n)
+	cfg.TargetHeight = rowHeightFor(images, cfg, n)
+	pages := JustifyWithPageSplits(images, cfg, maxPageHeight)
+	if len(pages) > 1 && !cfg.FillLastPage {
+		cfg.FillLastPage = true
+		pages = JustifyWithPageSplits(images, cfg, maxPageHeight)
+	}
+	return pages
+}
+
+// rowHeightFor returns the height of a row holding n images of average aspect.
+func rowHeightFor(images []ImageInfo, cfg Config, n int) int {
+	var total float64
+	for _, img := range images {
+		total += img.Aspect
+	}
+	avg := total / float64(len(images))
+	h := int(float64(cfg.contentWidth()-(n-1)*cfg.Spacing) / (float64(n) * avg))
+	return max(h, 1)
+}
+
+func fitOnePage(images []ImageInfo, cfg Config, maxPageHeight int) []Page {
+	minHeight := max(cfg.MinRowHeight, 1)
+	var pages []Page
+	for h := cfg.available(maxPageHeight); h >= minHeight; h -= fitStep {
+		cfg.TargetHeight = h
+		cfg.MinAspectTotal = 0
+		pages = JustifyWithPageSplits(images, cfg, maxPageHeight)
+		if len(pages) <= 1 {
+			return pages
+		}
+	}
+	if pages == nil {
+		cfg.TargetHeight = minHeight
+		pages = JustifyWithPageSplits(images, cfg, maxPageHeight)
+	}
+	return pages
+}
@@ -0,0 +1,130 @@
+package layout
+
+import "testing"
+
+func rowsOf(pages []Page) [][]PlacedImage {
+	var rows [][]PlacedImage
+	for _, page := range pages {
+		var current []PlacedImage
+		for _, p := range page.Images {
+			if len(current) > 0 && current[0].Y != p.Y {
+				rows = append(rows, current)
+				current = nil
+			}
+			current = append(current, p)
+		}
+		if len(current) > 0 {
+			rows = append(rows, current)
+		}
+	}
+	return rows
+}
+
+func TestParseMode(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"fit", "rows", "Compact", " medium ", "LARGE"} {
+		if _, err := ParseMode(s); err != nil {
+			t.Fatalf("unexpected error for %q: %v", s, err)
+		}
+	}
+	if _, err := ParseMode("huge"); err == nil {
+		t.Fatalf("expected error for unknown mode")
+	}
+}
+
+func TestArrange_ModesCapItemsPerRow(t *testing.T) {
+	t.Parallel()
+
+	for mode, maxItems := range modeMaxRowItems {
+		pages := Arrange(mixedImages(50), mode, defaultConfig(), 1920)
+		placed := 0
+		for _, r := range rowsOf(pages) {
+			if len(r) > maxItems {
+				t.Fatalf("%s: row with %d images, max %d", mode, len(r), maxItems)
+			}
+			placed += len(r)
+		}
+		if placed != 50 {
+			t.Fatalf("%s: expected 50 images placed, got %d", mode, placed)
+		}
+		for i, page := range pages {
+			if page.Height > 1920 {
+				t.Fatalf("%s: page %d is %d tall", mode, i+1, page.Height)
+			}
+		}
+	}
+}
+
+func TestArrange_LargeUsesOneImagePerRow(t *testing.T) {
+	t.Parallel()
+
+	pages := Arrange(uniformImages(5, 1.5), ModeLarge, defaultConfig(), 1920)
+	for _, r := range rowsOf(pages) {
+		if len(r) != 1 {
+			t.Fatalf("expected one image per row, got %d", len(r))
+		}
+		if r[0].Width != 1080 {
+			t.Fatalf("expected full-width image, got %d", r[0].Width)
+		}
+	}
+}
+
+func TestArrange_FitPutsEverythingOnOnePage(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultConfig()
+	cfg.MinRowHeight = 40
+	pages := Arrange(mixedImages(80), ModeFit, cfg, 1920)
+	if len(pages) != 1 {
+		t.Fatalf("expected one page, got %d", len(pages))
+	}
+	if len(pages[0].Images) != 80 {
+		t.Fatalf("expected 80 images, got %d", len(pages[0].Images))
+	}
+}
+
+func TestArrange_FitGrowsRowsForFewImages(t *testing.T) {
+	t.Parallel()
+
+	pages := Arrange(uniformImages(4, 0.75), ModeFit, defaultConfig(), 1920)
+	if len(pages) != 1 {
+		t.Fatalf("expected one page, got %d", len(pages))
+	}
+	if h := pages[0].Images[0].Height; h <= 320 {
+		t.Fatalf("expected rows taller than the default 320, got %d", h)
+	}
+}
+
+func TestArrange_RowsKeepsTargetHeight(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultConfig()
+	cfg.TargetHeight = 200
+	pages := Arrange(mixedImages(60), ModeRows, cfg, 1920)
+	if len(pages) < 2 {
+		t.Fatalf("expected rows split into pages, got %d page(s)", len(pages))
+	}
+	for _, r := range rowsOf(pages) {
+		if h := r[0].Height; h < 120 || h > 280 {
+			t.Fatalf("expected rows near the 200 target, got %d", h)
+		}
+	}
+	// The zero Mode behaves like ModeRows.
+	if zero := Arrange(mixedImages(60), "", cfg, 1920); len(zero) != len(pages) {
+		t.Fatalf("expected zero mode to match rows mode, got %d and %d pages", len(zero), len(pages))
+	}
+}
+
+func TestArrange_CompactKeepsFullRowsOnSinglePage(t *testing.T) {
+	t.Parallel()
+
+	pages := Arrange(uniformImages(7, 1.5), ModeCompact, defaultConfig(), 1920)
+	if len(pages) != 1 {
+		t.Fatalf("expected one page, got %d", len(pages))
+	}
+	// Filling the page would re-justify into rows of two or three.
+	if rows := rowsOf(pages); len(rows[0]) < 5 {
+		t.Fatalf("expected about 6 images in the first row, got %d", len(rows[0]))
+	}
+}
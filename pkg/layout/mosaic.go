@@ -0,0 +1,183 @@
+package layout
+
+import (
+	"math"
+	"sort"
+)
+
+// maxMosaicItems bounds images per mosaic page; the number of slicing trees
+// searched grows exponentially with it.
+const maxMosaicItems = 8
+
+// Mosaic fills every page edge to edge at exactly MaxWidth x maxPageHeight.
+// Each page is cut recursively into two parts, side by side or stacked, until
+// every image has its own cell; of all such guillotine partitions the one
+// needing the least cropping is kept. Images may then be matched to cells by
+// aspect ratio, and PlacedImage.Crop reports how much of each is cut away.
+// CaptionHeight is ignored: cells have no room below them, so captions are
+// expected to be drawn over the images.
+type Mosaic struct {
+	Config  Config // MaxWidth, Spacing, Margin, HeaderHeight and FooterHeight are used
+	PerPage int    // Images per page, at most maxMosaicItems
+}
+
+type rect struct {
+	x, y, w, h float64
+}
+
+func (r rect) aspect() float64 {
+	return r.w / r.h
+}
+
+func (m Mosaic) Arrange(images []ImageInfo, maxPageHeight int) []Page {
+	if len(images) == 0 {
+		return nil
+	}
+	cfg := m.Config
+	perPage := min(max(m.PerPage, 1), maxMosaicItems)
+	area := rect{
+		x: float64(cfg.Margin),
+		y: float64(cfg.Margin + cfg.HeaderHeight),
+		w: float64(cfg.contentWidth()),
+		h: float64(cfg.available(maxPageHeight)),
+	}
+
+	var pages []Page
+	start := 0
+	for _, count := range balancedCounts(len(images), perPage) {
+		indices := make([]int, count)
+		for i := range indices {
+			indices[i] = start + i
+		}
+		start += count
+
+		cells, _ := bestPartition(images, indices, area, float64(cfg.Spacing))
+		pages = append(pages, Page{Height: maxPageHeight, Images: assignCells(images, indices, cells)})
+	}
+	return pages
+}
+
+// balancedCounts splits n into as few pages of at most perPage as possible,
+// with page sizes differing by at most one.
+func balancedCounts(n, perPage int) []int {
+	pages := (n + perPage - 1) / perPage
+	counts := make([]int, pages)
+	for i := range counts {
+		counts[i] = n / pages
+		if i < n%pages {
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// cropFraction is the share of an image of the given aspect that is cut away
+// when it is scaled to cover a cell of cellAspect.
+func cropFraction(aspect, cellAspect float64) float64 {
+	return 1 - math.Min(aspect, cellAspect)/math.Max(aspect, cellAspect)
+}
+
+// bestPartition returns cells for indices (in order) and their total crop.
+// Side by side, each part gets width in proportion to its summed aspect, as
+// if its images shared one height; stacked, height in proportion to summed
+// inverse aspect.
+func bestPartition(images []ImageInfo, indices []int, r rect, spacing float64) ([]rect, float64) {
+	if len(indices) == 1 {
+		return []rect{r}, cropFraction(images[indices[0]].Aspect, r.aspect())
+	}
+
+	var (
+		best     []rect
+		bestCost = math.Inf(1)
+	)
+	for split := 1; split < len(indices); split++ {
+		left, right := indices[:split], indices[split:]
+
+		var wl, wr float64
+		for _, i := range left {
+			wl += images[i].Aspect
+		}
+		for _, i := range right {
+			wr += images[i].Aspect
+		}
+		width := (r.w - spacing) * wl / (wl + wr)
+		a := rect{x: r.x, y: r.y, w: width, h: r.h}
+		b := rect{x: r.x + width + spacing, y: r.y, w: r.w - width - spacing, h: r.h}
+		if cells, cost := splitCost(images, left, right, a, b, spacing); cost < bestCost {
+			best, bestCost = cells, cost
+		}
+
+		var hl, hr float64
+		for _, i := range left {
+			hl += 1 / images[i].Aspect
+		}
+		for _, i := range right {
+			hr += 1 / images[i].Aspect
+		}
+		height := (r.h - spacing) * hl / (hl + hr)
+		a = rect{x: r.x, y: r.y, w: r.w, h: height}
+		b = rect{x: r.x, y: r.y + height + spacing, w: r.w, h: r.h - height - spacing}
+		if cells, cost := splitCost(images, left, right, a, b, spacing); cost < bestCost {
+			best, bestCost = cells, cost
+		}
+	}
+	return best, bestCost
+}
+
+func splitCost(images []ImageInfo, left, right []int, a, b rect, spacing float64) ([]rect, float64) {
+	if a.w <= 0 || a.h <= 0 || b.w <= 0 || b.h <= 0 {
+		return nil, math.Inf(1)
+	}
+	ca, costA := bestPartition(images, left, a, spacing)
+	cb, costB := bestPartition(images, right, b, spacing)
+	return append(append([]rect{}, ca...), cb...), costA + costB
+}
+
+// assignCells places images in their partition order or paired with cells by
+// rank of aspect ratio, whichever crops less, and snaps cells to whole pixels.
+func assignCells(images []ImageInfo, indices []int, cells []rect) []PlacedImage {
+	byImage := append([]int{}, indices...)
+	sort.SliceStable(byImage, func(i, j int) bool {
+		return images[byImage[i]].Aspect < images[byImage[j]].Aspect
+	})
+	byCell := make([]int, len(cells))
+	for i := range byCell {
+		byCell[i] = i
+	}
+	sort.SliceStable(byCell, func(i, j int) bool {
+		return cells[byCell[i]].aspect() < cells[byCell[j]].aspect()
+	})
+	ranked := make([]int, len(cells))
+	for k, c := range byCell {
+		ranked[c] = byImage[k]
+	}
+
+	assignment := indices
+	if totalCrop(images, ranked, cells) < totalCrop(images, indices, cells) {
+		assignment = ranked
+	}
+
+	placed := make([]PlacedImage, len(cells))
+	for c, r := range cells {
+		x0, y0 := int(math.Round(r.x)), int(math.Round(r.y))
+		x1, y1 := int(math.Round(r.x+r.w)), int(math.Round(r.y+r.h))
+		idx := assignment[c]
+		placed[c] = PlacedImage{
+			Index:  idx,
+			X:      x0,
+			Y:      y0,
+			Width:  x1 - x0,
+			Height: y1 - y0,
+			Crop:   cropFraction(images[idx].Aspect, r.aspect()),
+		}
+	}
+	return placed
+}
+
+func totalCrop(images []ImageInfo, assignment []int, cells []rect) float64 {
+	var total float64
+	for c, r := range cells {
+		total += cropFraction(images[assignment[c]].Aspect, r.aspect())
+	}
+	return total
+}
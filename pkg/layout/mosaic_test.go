@@ -0,0 +1,103 @@
+package layout
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBalancedCounts(t *testing.T) {
+	t.Parallel()
+
+	got := balancedCounts(11, 5)
+	want := []int{4, 4, 3}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestMosaic_FillsPageExactly(t *testing.T) {
+	t.Parallel()
+
+	m := Mosaic{Config: Config{MaxWidth: 1080}, PerPage: 6}
+	pages := m.Arrange(mixedImages(17), 1350)
+
+	placed := 0
+	for i, page := range pages {
+		if page.Height != 1350 {
+			t.Fatalf("page %d: expected height 1350, got %d", i+1, page.Height)
+		}
+		area := 0
+		for j, a := range page.Images {
+			area += a.Width * a.Height
+			if a.X < 0 || a.Y < 0 || a.X+a.Width > 1080 || a.Y+a.Height > 1350 {
+				t.Fatalf("page %d: cell %+v outside the page", i+1, a)
+			}
+			for _, b := range page.Images[j+1:] {
+				if overlaps(a, b) {
+					t.Fatalf("page %d: cells %+v and %+v overlap", i+1, a, b)
+				}
+			}
+		}
+		if area != 1080*1350 {
+			t.Fatalf("page %d: cells cover %d px, expected %d", i+1, area, 1080*1350)
+		}
+		placed += len(page.Images)
+	}
+	if placed != 17 {
+		t.Fatalf("expected 17 images placed, got %d", placed)
+	}
+}
+
+func TestMosaic_NoCropWhenAspectsTile(t *testing.T) {
+	t.Parallel()
+
+	// Two squares side by side exactly fill a 2:1 page.
+	m := Mosaic{Config: Config{MaxWidth: 1000}, PerPage: 2}
+	pages := m.Arrange(uniformImages(2, 1), 500)
+
+	for _, p := range pages[0].Images {
+		if p.Crop > 1e-9 {
+			t.Fatalf("expected no crop, got %.3f for %+v", p.Crop, p)
+		}
+		if p.Width != 500 || p.Height != 500 {
+			t.Fatalf("expected 500x500 cells, got %+v", p)
+		}
+	}
+}
+
+func TestMosaic_ReportsCrop(t *testing.T) {
+	t.Parallel()
+
+	m := Mosaic{Config: Config{MaxWidth: 1080}, PerPage: 1}
+	pages := m.Arrange([]ImageInfo{{Aspect: 1.5}}, 1350)
+
+	got := pages[0].Images[0].Crop
+	want := 1 - 0.8/1.5
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("expected crop %.3f, got %.3f", want, got)
+	}
+}
+
+func TestMosaic_RespectsSpacingAndMargin(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{MaxWidth: 1080, Spacing: 8, Margin: 24}
+	pages := Mosaic{Config: cfg, PerPage: 5}.Arrange(mixedImages(5), 1350)
+
+	for j, a := range pages[0].Images {
+		if a.X < 24 || a.Y < 24 || a.X+a.Width > 1080-24 || a.Y+a.Height > 1350-24 {
+			t.Fatalf("cell %+v inside the margin", a)
+		}
+		for _, b := range pages[0].Images[j+1:] {
+			grown := PlacedImage{X: a.X - 7, Y: a.Y - 7, Width: a.Width + 14, Height: a.Height + 14}
+			if overlaps(grown, b) {
+				t.Fatalf("cells %+v and %+v closer than the spacing", a, b)
+			}
+		}
+	}
+}
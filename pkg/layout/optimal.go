@@ -0,0 +1,75 @@
+package layout
+
+import "math"
+
+// maxOptimalImages bounds the input of optimalRows; larger sets fall back to
+// greedy breaking, which is linear and needs no extra memory.
+const maxOptimalImages = 5000
+
+// shortRowPenalty is added per missing image when a row holds fewer than
+// MinRowItems, so such rows are only chosen when nothing else fits.
+const shortRowPenalty = 1e7
+
+// optimalRows breaks images into rows the way Knuth–Plass breaks paragraphs:
+// every possible row is scored by its squared deviation from TargetHeight and
+// dynamic programming picks the breaks with the lowest total score. Unlike the
+// greedy breaker, the last row is justified like the others.
+func optimalRows(images []ImageInfo, cfg Config) []row {
+	n := len(images)
+	if n == 0 {
+		return nil
+	}
+	target := float64(cfg.TargetHeight)
+
+	// cost[i] is the best score for images[:i]; from[i] is where its last row starts.
+	cost := make([]float64, n+1)
+	from := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		cost[i] = math.Inf(1)
+	}
+
+	for start := 0; start < n; start++ {
+		if math.IsInf(cost[start], 1) {
+			continue
+		}
+		var aspect float64
+		for end := start + 1; end <= n; end++ {
+			count := end - start
+			if cfg.MaxRowItems > 0 && count > cfg.MaxRowItems {
+				break
+			}
+			aspect += images[end-1].Aspect
+			height := float64(cfg.contentWidth()-(count-1)*cfg.Spacing) / aspect
+
+			c := cost[start] + (height-target)*(height-target)
+			if count < cfg.MinRowItems {
+				c += shortRowPenalty * float64(cfg.MinRowItems-count)
+			}
+			if c < cost[end] {
+				cost[end] = c
+				from[end] = start
+			}
+			// Rows only get shorter from here; stop once they are far too short.
+			if height < target/4 {
+				break
+			}
+		}
+	}
+
+	var rows []row
+	for end := n; end > 0; end = from[end] {
+		start := from[end]
+		count := end - start
+		var aspect float64
+		for _, img := range images[start:end] {
+			aspect += img.Aspect
+		}
+		natural := float64(cfg.contentWidth()-(count-1)*cfg.Spacing) / aspect
+		height := cfg.capHeight(natural)
+		rows = append(rows, row{start: start, count: count, height: height, full: height == natural})
+	}
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+	return rows
+}
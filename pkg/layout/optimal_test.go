@@ -0,0 +1,108 @@
+package layout
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func formatRows(rows []row) string {
+	var b strings.Builder
+	for _, r := range rows {
+		fmt.Fprintf(&b, "%d-%d h=%d\n", r.start, r.start+r.count-1, int(math.Round(r.height)))
+	}
+	return b.String()
+}
+
+func deviation(rows []row, target float64) float64 {
+	var total float64
+	for _, r := range rows {
+		total += (r.height - target) * (r.height - target)
+	}
+	return total
+}
+
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if got != string(want) {
+		t.Fatalf("%s mismatch\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
+
+var goldenSets = map[string][]ImageInfo{
+	"mixed":     mixedImages(23),
+	"portrait":  uniformImages(11, 0.67),
+	"panoramas": {{3.2}, {0.67}, {0.67}, {2.4}, {1.5}, {0.8}, {4.0}, {1.0}, {0.56}},
+}
+
+func TestRowBreaking_Golden(t *testing.T) {
+	for name, images := range goldenSets {
+		for _, breaking := range []RowBreaking{BreakGreedy, BreakOptimal} {
+			cfg := defaultConfig()
+			cfg.Breaking = breaking
+			assertGolden(t, name+"_"+string(breaking), formatRows(justifyRows(images, cfg)))
+		}
+	}
+}
+
+func TestOptimalRows_NoWorseThanGreedy(t *testing.T) {
+	t.Parallel()
+
+	for name, images := range goldenSets {
+		cfg := defaultConfig()
+		greedy := deviation(greedyRows(images, cfg), float64(cfg.TargetHeight))
+		optimal := deviation(optimalRows(images, cfg), float64(cfg.TargetHeight))
+		if optimal > greedy {
+			t.Fatalf("%s: optimal deviation %.0f above greedy %.0f", name, optimal, greedy)
+		}
+	}
+}
+
+func TestOptimalRows_CoversAllImagesInOrder(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultConfig()
+	cfg.MaxRowItems = 3
+	rows := optimalRows(mixedImages(40), cfg)
+	next := 0
+	for _, r := range rows {
+		if r.start != next {
+			t.Fatalf("expected row to start at %d, got %d", next, r.start)
+		}
+		if r.count > 3 {
+			t.Fatalf("row with %d images exceeds MaxRowItems", r.count)
+		}
+		next += r.count
+	}
+	if next != 40 {
+		t.Fatalf("expected 40 images, got %d", next)
+	}
+}
+
+func TestParseRowBreaking(t *testing.T) {
+	t.Parallel()
+
+	if b, err := ParseRowBreaking("Optimal"); err != nil || b != BreakOptimal {
+		t.Fatalf("expected optimal, got %q (%v)", b, err)
+	}
+	if _, err := ParseRowBreaking("best"); err == nil {
+		t.Fatalf("expected error for unknown row breaking")
+	}
+}
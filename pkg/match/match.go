@@ -0,0 +1,203 @@
+// Package match selects files with wildcard patterns, where "..." stands for
+// any number of directories, and excludes them with .gitignore-like rules.
+package match
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// splitPattern breaks a pattern into slash separated segments. Each segment is
+// matched with path.Match, and a "..." segment matches any number of
+// directories, including none, so "build/..." matches build itself and
+// everything below it but not buildinfo.go.
+func splitPattern(pattern string) []string {
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	if pattern == "." {
+		return nil
+	}
+	return strings.Split(pattern, "/")
+}
+
+func splitPath(p string) []string {
+	p = path.Clean(filepath.ToSlash(p))
+	if p == "." {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func hasWildcard(segment string) bool {
+	return segment == "..." || strings.ContainsAny(segment, "*?[")
+}
+
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "..." {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], segments[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// matchPrefix reports whether a path starting with segments may still match.
+func matchPrefix(pattern, segments []string) bool {
+	if len(segments) == 0 {
+		return true
+	}
+	if len(pattern) == 0 {
+		return false
+	}
+	if pattern[0] == "..." {
+		return true
+	}
+	ok, err := path.Match(pattern[0], segments[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchPrefix(pattern[1:], segments[1:])
+}
+
+type exclusionRule struct {
+	segments []string
+	negate   bool
+}
+
+// Matcher decides which paths are excluded. Rules are evaluated in order and
+// the last one that matches wins, so "!keep.go" after "*.go" keeps keep.go.
+// A pattern without a slash (other than a trailing "/...") matches at any
+// depth; a leading "./" anchors it to the current directory. As with
+// .gitignore, a file cannot be re-included once its parent directory is
+// excluded, which lets excluded directories be skipped without walking them.
+type Matcher struct {
+	rules []exclusionRule
+}
+
+// New compiles exclusion patterns; see Matcher for the syntax.
+func New(patterns []string) *Matcher {
+	m := &Matcher{}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		negate := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		if p == "" {
+			continue
+		}
+		anchored := strings.HasPrefix(filepath.ToSlash(p), "./") || filepath.IsAbs(p)
+		segments := splitPattern(p)
+		if len(segments) == 0 {
+			continue
+		}
+		literal := segments
+		if literal[len(literal)-1] == "..." {
+			literal = literal[:len(literal)-1]
+		}
+		if !anchored && len(literal) == 1 && literal[0] != "..." {
+			segments = append([]string{"..."}, segments...)
+		}
+		m.rules = append(m.rules, exclusionRule{segments: segments, negate: negate})
+	}
+	return m
+}
+
+// Match applies the rules to a single path, ignoring its parents.
+func (m *Matcher) Match(p string) bool {
+	segments := splitPath(p)
+	excluded := false
+	for _, r := range m.rules {
+		if matchSegments(r.segments, segments) {
+			excluded = !r.negate
+		}
+	}
+	return excluded
+}
+
+// Excluded reports whether p or any of its parent directories is excluded.
+func (m *Matcher) Excluded(p string) bool {
+	segments := splitPath(p)
+	for i := 1; i <= len(segments); i++ {
+		if m.Match(strings.Join(segments[:i], "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand resolves input patterns to file paths, skipping excluded
+// directories during the walk instead of filtering afterwards.
+func Expand(inputs []string, excl *Matcher) []string {
+	var expanded []string
+	seen := map[string]bool{}
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			expanded = append(expanded, p)
+		}
+	}
+
+	for _, input := range inputs {
+		segments := splitPattern(input)
+		wildcard := -1
+		for i, s := range segments {
+			if hasWildcard(s) {
+				wildcard = i
+				break
+			}
+		}
+
+		if wildcard < 0 {
+			p := path.Clean(filepath.ToSlash(input))
+			if !excl.Excluded(p) {
+				add(p)
+			}
+			continue
+		}
+
+		root := strings.Join(segments[:wildcard], "/")
+		if root == "" {
+			root = "."
+			if strings.HasPrefix(filepath.ToSlash(input), "/") {
+				root = "/"
+			}
+		}
+		if root != "." && excl.Excluded(root) {
+			continue
+		}
+
+		_ = filepath.WalkDir(root, func(fp string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			p := filepath.ToSlash(fp)
+			if d.IsDir() {
+				if p == root {
+					return nil
+				}
+				if excl.Match(p) || !matchPrefix(segments, splitPath(p)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if matchSegments(segments, splitPath(p)) && !excl.Match(p) {
+				add(p)
+			}
+			return nil
+		})
+	}
+	return expanded
+}
@@ -0,0 +1,155 @@
+package match
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func createTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, f := range files {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return root
+}
+
+func relPaths(t *testing.T, root string, paths []string) []string {
+	t.Helper()
+	var rel []string
+	for _, p := range paths {
+		r, err := filepath.Rel(root, filepath.FromSlash(p))
+		if err != nil {
+			t.Fatalf("failed to make %s relative: %v", p, err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	sort.Strings(rel)
+	return rel
+}
+
+func assertPaths(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestMatcher_DirectoryPatternDoesNotMatchPrefix(t *testing.T) {
+	t.Parallel()
+
+	m := New([]string{"build/..."})
+	if !m.Excluded("build") {
+		t.Fatalf("expected build to be excluded")
+	}
+	if !m.Excluded("build/out/app.bin") {
+		t.Fatalf("expected build/out/app.bin to be excluded")
+	}
+	if m.Excluded("buildinfo.go") {
+		t.Fatalf("expected buildinfo.go not to be excluded")
+	}
+	if m.Excluded("cmd/buildinfo/main.go") {
+		t.Fatalf("expected cmd/buildinfo/main.go not to be excluded")
+	}
+}
+
+func TestMatcher_UnanchoredMatchesAtAnyDepth(t *testing.T) {
+	t.Parallel()
+
+	m := New([]string{"node_modules/...", "go.sum"})
+	for _, p := range []string{"node_modules/a.js", "web/app/node_modules/x/y.js", "go.sum", "tools/go.sum"} {
+		if !m.Excluded(p) {
+			t.Fatalf("expected %s to be excluded", p)
+		}
+	}
+}
+
+func TestMatcher_AnchoredMatchesFromRootOnly(t *testing.T) {
+	t.Parallel()
+
+	m := New([]string{"./dist/..."})
+	if !m.Excluded("dist/main.js") {
+		t.Fatalf("expected dist/main.js to be excluded")
+	}
+	if m.Excluded("web/dist/main.js") {
+		t.Fatalf("expected web/dist/main.js not to be excluded")
+	}
+}
+
+func TestMatcher_NegationReincludesFile(t *testing.T) {
+	t.Parallel()
+
+	m := New([]string{"*.go", "!keep.go"})
+	if !m.Excluded("pkg/drop.go") {
+		t.Fatalf("expected pkg/drop.go to be excluded")
+	}
+	if m.Excluded("pkg/keep.go") {
+		t.Fatalf("expected pkg/keep.go to be kept")
+	}
+}
+
+func TestMatcher_NegationCannotReincludeUnderExcludedDir(t *testing.T) {
+	t.Parallel()
+
+	m := New([]string{"vendor/...", "!keep.go"})
+	if !m.Excluded("vendor/lib/keep.go") {
+		t.Fatalf("expected vendor/lib/keep.go to stay excluded")
+	}
+}
+
+func TestExpandInputs_RecursiveGlob(t *testing.T) {
+	t.Parallel()
+
+	root := createTree(t, "a.go", "a.md", "sub/b.go", "sub/deep/c.go")
+	got := relPaths(t, root, Expand([]string{filepath.ToSlash(root) + "/.../*.go"}, New(nil)))
+
+	assertPaths(t, got, []string{"a.go", "sub/b.go", "sub/deep/c.go"})
+}
+
+func TestExpandInputs_SingleLevelGlob(t *testing.T) {
+	t.Parallel()
+
+	root := createTree(t, "config.yaml", "config.json", "main.go", "sub/config.toml")
+	got := relPaths(t, root, Expand([]string{filepath.ToSlash(root) + "/config*"}, New(nil)))
+
+	assertPaths(t, got, []string{"config.json", "config.yaml"})
+}
+
+func TestExpandInputs_PrunesExcludedDirsAndKeepsNegated(t *testing.T) {
+	t.Parallel()
+
+	root := createTree(t,
+		"main.go",
+		"buildinfo.go",
+		"build/out.go",
+		"node_modules/pkg/index.go",
+		"gen/a_gen.go",
+		"gen/keep_gen.go",
+	)
+	excl := New([]string{"build/...", "node_modules/...", "*_gen.go", "!keep_gen.go"})
+	got := relPaths(t, root, Expand([]string{filepath.ToSlash(root) + "/..."}, excl))
+
+	assertPaths(t, got, []string{"buildinfo.go", "gen/keep_gen.go", "main.go"})
+}
+
+func TestExpandInputs_SkipsExcludedPlainPath(t *testing.T) {
+	t.Parallel()
+
+	excl := New([]string{"vendor/..."})
+	got := Expand([]string{"vendor/lib/a.go", "main.go"}, excl)
+
+	assertPaths(t, got, []string{"main.go"})
+}
@@ -0,0 +1,273 @@
+// Package pdf writes PDF files whose pages each hold one JPEG image scaled to
+// the full page. The JPEG data is embedded as is (DCTDecode), so no pixels are
+// re-encoded and files stay about as large as the images themselves.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Size is a page size in points (1/72 inch).
+type Size struct {
+	W, H float64
+}
+
+var (
+	A4     = Size{595.28, 841.89}
+	Letter = Size{612, 792}
+)
+
+// ParsePaper returns the portrait size of a named paper format.
+func ParsePaper(s string) (Size, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "a4":
+		return A4, nil
+	case "letter":
+		return Letter, nil
+	}
+	return Size{}, fmt.Errorf("unknown paper %q (use a4 or letter)", s)
+}
+
+// Landscape returns the size with its longer side horizontal.
+func (s Size) Landscape() Size {
+	if s.W < s.H {
+		return Size{s.H, s.W}
+	}
+	return s
+}
+
+// Pixels is the size in pixels at dpi.
+func (s Size) Pixels(dpi int) image.Point {
+	return image.Pt(int(s.W*float64(dpi)/72+0.5), int(s.H*float64(dpi)/72+0.5))
+}
+
+// SizeAt is the page size of an image w x h pixels printed at dpi.
+func SizeAt(w, h, dpi int) Size {
+	return Size{float64(w) * 72 / float64(dpi), float64(h) * 72 / float64(dpi)}
+}
+
+// Writer streams pages to w. Objects 1 and 2 are reserved for the catalog and
+// page tree, which are written by Close once all pages are known.
+type Writer struct {
+	w       io.Writer
+	n       int64
+	err     error
+	offsets map[int]int64
+	next    int
+	pages   []int
+}
+
+func NewWriter(w io.Writer) *Writer {
+	pw := &Writer{w: w, offsets: map[int]int64{}, next: 3}
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	return pw
+}
+
+func (pw *Writer) printf(format string, args ...any) {
+	if pw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(pw.w, format, args...)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+func (pw *Writer) write(b []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(b)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+func (pw *Writer) begin(id int) {
+	pw.offsets[id] = pw.n
+	pw.printf("%d 0 obj\n", id)
+}
+
+func (pw *Writer) alloc() int {
+	id := pw.next
+	pw.next++
+	return id
+}
+
+// AddImage encodes img as a JPEG at quality and adds it as a page of size.
+// Transparency is flattened onto white.
+func (pw *Writer) AddImage(img image.Image, size Size, quality int) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality}); err != nil {
+		return err
+	}
+	return pw.AddJPEG(buf.Bytes(), size)
+}
+
+// AddJPEG adds a page of size showing the JPEG data stretched to fill it.
+func (pw *Writer) AddJPEG(data []byte, size Size) error {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid JPEG: %w", err)
+	}
+	colorSpace, decode := "/DeviceRGB", ""
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		colorSpace = "/DeviceGray"
+	case color.CMYKModel:
+		// Adobe CMYK JPEGs store inverted values.
+		colorSpace, decode = "/DeviceCMYK", " /Decode [1 0 1 0 1 0 1 0]"
+	}
+
+	page, contents, im := pw.alloc(), pw.alloc(), pw.alloc()
+
+	pw.begin(im)
+	pw.printf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8%s /Filter /DCTDecode /Length %d >>\nstream\n",
+		cfg.Width, cfg.Height, colorSpace, decode, len(data))
+	pw.write(data)
+	pw.printf("\nendstream\nendobj\n")
+
+	draw := fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", size.W, size.H)
+	pw.begin(contents)
+	pw.printf("<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(draw), draw)
+
+	pw.begin(page)
+	pw.printf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+		size.W, size.H, im, contents)
+
+	pw.pages = append(pw.pages, page)
+	return pw.err
+}
+
+// Close writes the page tree, catalog and cross-reference table. It does not
+// close the underlying writer.
+func (pw *Writer) Close() error {
+	if len(pw.pages) == 0 {
+		return fmt.Errorf("pdf has no pages")
+	}
+
+	kids := make([]string, len(pw.pages))
+	for i, id := range pw.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	pw.begin(2)
+	pw.printf("<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(pw.pages))
+	pw.begin(1)
+	pw.printf("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	xref := pw.n
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", pw.next)
+	for id := 1; id < pw.next; id++ {
+		pw.printf("%010d 00000 n \n", pw.offsets[id])
+	}
+	pw.printf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", pw.next, xref)
+	return pw.err
+}
+
+// File is a Writer that owns the file it writes to.
+type File struct {
+	*Writer
+	f      *os.File
+	closed bool
+}
+
+// Create creates the PDF file at path.
+func Create(path string) (*File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Writer: NewWriter(f), f: f}, nil
+}
+
+// Close finishes the document and closes the file. Calls after the first
+// do nothing, so Close can also be deferred for error paths.
+func (f *File) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	err := f.Writer.Close()
+	if cerr := f.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// flatten composites images with an alpha channel onto white, as JPEG has no
+// transparency.
+func flatten(img image.Image) image.Image {
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		return img
+	}
+	opaque := true
+	for i := 3; i < len(nrgba.Pix); i += 4 {
+		if nrgba.Pix[i] != 255 {
+			opaque = false
+			break
+		}
+	}
+	if opaque {
+		return img
+	}
+	b := nrgba.Bounds()
+	out := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := nrgba.NRGBAAt(x, y)
+			a := uint32(c.A)
+			blend := func(v uint8) uint8 { return uint8((uint32(v)*a + 255*(255-a)) / 255) }
+			out.Set(x, y, color.RGBA{blend(c.R), blend(c.G), blend(c.B), 255})
+		}
+	}
+	return out
+}
+
+// SavePage writes img to path, if set, in the format of its extension, and
+// adds it as a page printed at dpi to doc, if set. JPEG pages are encoded
+// once and the same bytes go into both.
+func SavePage(img *image.NRGBA, path string, doc *File, dpi, quality int) error {
+	if dpi <= 0 {
+		return fmt.Errorf("dpi must be positive, got %d", dpi)
+	}
+	size := SizeAt(img.Bounds().Dx(), img.Bounds().Dy(), dpi)
+
+	format := imaging.JPEG
+	if path != "" {
+		var err error
+		if format, err = imaging.FormatFromFilename(path); err != nil {
+			return err
+		}
+	}
+	if format != imaging.JPEG {
+		if err := imaging.Save(img, path); err != nil {
+			return err
+		}
+		if doc != nil {
+			return doc.AddImage(img, size, quality)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, imaging.JPEG, imaging.JPEGQuality(quality)); err != nil {
+		return err
+	}
+	if path != "" {
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	if doc != nil {
+		return doc.AddJPEG(buf.Bytes(), size)
+	}
+	return nil
+}
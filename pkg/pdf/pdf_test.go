@@ -0,0 +1,141 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriter_ProducesValidXref(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	pw := NewWriter(&buf)
+	if err := pw.AddImage(image.NewNRGBA(image.Rect(0, 0, 40, 30)), A4, 90); err != nil {
+		t.Fatal(err)
+	}
+	var gray bytes.Buffer
+	if err := jpeg.Encode(&gray, image.NewGray(image.Rect(0, 0, 20, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.AddJPEG(gray.Bytes(), Letter.Landscape()); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	for _, want := range []string{"/Count 2", "/DeviceRGB", "/DeviceGray", "/MediaBox [0 0 792.00 612.00]"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Fatalf("expected %q in output", want)
+		}
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatalf("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	lines := strings.Split(string(out[xref:]), "\n")
+	if lines[0] != "xref" {
+		t.Fatalf("startxref does not point at the xref table")
+	}
+	var count int
+	fmt.Sscanf(lines[1], "0 %d", &count)
+	for id := 1; id < count; id++ {
+		offset, _ := strconv.Atoi(lines[2+id][:10])
+		if want := fmt.Sprintf("%d 0 obj\n", id); !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d points at %q", id, out[offset:offset+10])
+		}
+	}
+}
+
+func TestWriter_NoPages(t *testing.T) {
+	t.Parallel()
+
+	if err := NewWriter(&bytes.Buffer{}).Close(); err == nil {
+		t.Fatalf("expected error for a PDF without pages")
+	}
+}
+
+func TestFlatten_OntoWhite(t *testing.T) {
+	t.Parallel()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 0})
+	r, g, b, _ := flatten(img).At(0, 0).RGBA()
+	if r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+		t.Fatalf("expected transparent pixel to become white, got %d %d %d", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestPaper(t *testing.T) {
+	t.Parallel()
+
+	a4, err := ParsePaper("A4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if px := a4.Pixels(300); px != image.Pt(2480, 3508) {
+		t.Fatalf("expected A4 at 300 dpi to be 2480x3508, got %v", px)
+	}
+	if _, err := ParsePaper("a3"); err == nil {
+		t.Fatalf("expected error for unknown paper")
+	}
+}
+
+func TestSavePage(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	doc, err := Create(filepath.Join(dir, "out.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 150))
+	jpg := filepath.Join(dir, "page.jpg")
+	if err := SavePage(img, jpg, doc, 150, 90); err != nil {
+		t.Fatal(err)
+	}
+	png := filepath.Join(dir, "page.png")
+	if err := SavePage(img, png, doc, 150, 90); err != nil {
+		t.Fatal(err)
+	}
+	if err := SavePage(img, "", doc, 0, 90); err == nil {
+		t.Fatal("expected error for dpi 0")
+	}
+	if err := doc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The JPEG file holds the same bytes that were embedded in the PDF.
+	data, err := os.ReadFile(jpg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, "out.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, data) {
+		t.Fatal("JPEG page was encoded twice")
+	}
+	if !bytes.Contains(out, []byte("/Count 2")) || !bytes.Contains(out, []byte("/MediaBox [0 0 144.00 72.00]")) {
+		t.Fatal("expected two pages of 2x1 inches")
+	}
+	if head, err := os.ReadFile(png); err != nil || !bytes.HasPrefix(head, []byte("\x89PNG")) {
+		t.Fatalf("expected a PNG file, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package smartcrop
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FocusMap holds manual focus regions keyed by image path as written in the
+// sidecar file.
+type FocusMap map[string]Focus
+
+// ReadFocusFile reads a sidecar file with one image per line:
+//
+//	path<TAB>x,y         focal point
+//	path<TAB>x,y,w,h     region, e.g. a face
+//
+// Values up to 1 are fractions of the image size; larger values are pixels.
+// Blank lines and lines starting with # are ignored.
+func ReadFocusFile(path string) (FocusMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	focus := FocusMap{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		image, spec, ok := strings.Cut(line, "\t")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected path<TAB>x,y[,w,h]", path, n)
+		}
+		fc, err := parseFocus(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		focus[strings.Trim(strings.TrimSpace(image), `"`)] = fc
+	}
+	return focus, scanner.Err()
+}
+
+func parseFocus(spec string) (Focus, error) {
+	parts := strings.Split(spec, ",")
+	if len(parts) != 2 && len(parts) != 4 {
+		return Focus{}, fmt.Errorf("focus %q needs x,y or x,y,w,h", spec)
+	}
+	var v [4]float64
+	pixels := false
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || f < 0 {
+			return Focus{}, fmt.Errorf("invalid focus value %q", p)
+		}
+		v[i] = f
+		pixels = pixels || f > 1
+	}
+	return Focus{X: v[0], Y: v[1], W: v[2], H: v[3], Pixels: pixels}, nil
+}
+
+// Lookup finds the focus of an image by its path, falling back to its base
+// name so a sidecar can list bare file names.
+func (m FocusMap) Lookup(path string) (Focus, bool) {
+	if f, ok := m[path]; ok {
+		return f, true
+	}
+	f, ok := m[filepath.Base(path)]
+	return f, ok
+}
@@ -0,0 +1,45 @@
+package smartcrop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFocusFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "focus.txt")
+	content := "# faces\nportrait.jpg\t0.5,0.2\nshots/group.jpg\t120,40,200,260\n\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	focus, err := ReadFocusFile(path)
+	if err != nil {
+		t.Fatalf("ReadFocusFile: %v", err)
+	}
+	if f, ok := focus.Lookup("/photos/portrait.jpg"); !ok || f != (Focus{X: 0.5, Y: 0.2}) {
+		t.Fatalf("expected point focus by base name, got %+v, %v", f, ok)
+	}
+	if f, ok := focus.Lookup("shots/group.jpg"); !ok || !f.Pixels || f.W != 200 {
+		t.Fatalf("expected pixel region, got %+v, %v", f, ok)
+	}
+	if _, ok := focus.Lookup("other.jpg"); ok {
+		t.Fatalf("expected no focus for unlisted image")
+	}
+}
+
+func TestReadFocusFile_Errors(t *testing.T) {
+	t.Parallel()
+
+	for _, content := range []string{"a.jpg 0.5,0.5\n", "a.jpg\t0.5\n", "a.jpg\t0.5,x\n", "a.jpg\t-1,0\n"} {
+		path := filepath.Join(t.TempDir(), "focus.txt")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadFocusFile(path); err == nil {
+			t.Fatalf("expected error for %q", content)
+		}
+	}
+}
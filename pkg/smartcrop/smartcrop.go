@@ -0,0 +1,216 @@
+package smartcrop
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// analysisSize is the length of the cropped axis when measuring energy; the
+// choice of window does not need full resolution.
+const analysisSize = 256
+
+// Strategy selects how the crop window is placed along the overflowing axis.
+type Strategy string
+
+const (
+	Center    Strategy = "center"    // Keep the middle
+	Entropy   Strategy = "entropy"   // Keep the most edge energy
+	Attention Strategy = "attention" // Keep the most salient region: detail, skin tones and saturated colour
+)
+
+func ParseStrategy(s string) (Strategy, error) {
+	st := Strategy(strings.ToLower(strings.TrimSpace(s)))
+	switch st {
+	case Center, Entropy, Attention:
+		return st, nil
+	}
+	return "", fmt.Errorf("unknown crop strategy %q (use center, entropy or attention)", s)
+}
+
+// Focus is a region of interest, such as a face, in fractions of the image
+// size or, with Pixels, in pixels of the original image. A point has zero
+// width and height.
+type Focus struct {
+	X, Y, W, H float64
+	Pixels     bool
+}
+
+// Fill scales img to cover w x h and crops the window along the overflowing
+// axis that holds the most edge energy, so detailed subjects are kept in
+// preference to flat background.
+func Fill(img image.Image, w, h int) *image.NRGBA {
+	return FillWith(img, w, h, Entropy)
+}
+
+// FillWith scales img to cover w x h and crops it according to strategy.
+func FillWith(img image.Image, w, h int, strategy Strategy) *image.NRGBA {
+	return fill(img, w, h, func(scaled *image.NRGBA, horizontal bool, length, window int) int {
+		switch strategy {
+		case Center:
+			return (length - window) / 2
+		case Attention:
+			return bestOffset(scaled, horizontal, w, h, attentionProfile)
+		}
+		return bestOffset(scaled, horizontal, w, h, energyProfile)
+	})
+}
+
+// FillAt scales img to cover w x h and centres the crop window on focus as
+// far as the image edges allow.
+func FillAt(img image.Image, w, h int, focus Focus) *image.NRGBA {
+	if b := img.Bounds(); focus.Pixels && b.Dx() > 0 && b.Dy() > 0 {
+		sx, sy := float64(b.Dx()), float64(b.Dy())
+		focus = Focus{X: focus.X / sx, Y: focus.Y / sy, W: focus.W / sx, H: focus.H / sy}
+	}
+	return fill(img, w, h, func(_ *image.NRGBA, horizontal bool, length, window int) int {
+		centre := focus.Y + focus.H/2
+		if horizontal {
+			centre = focus.X + focus.W/2
+		}
+		offset := int(math.Round(centre*float64(length))) - window/2
+		return min(max(offset, 0), length-window)
+	})
+}
+
+// fill scales img to cover w x h and crops the overflowing axis at the offset
+// returned by place, given the scaled length of that axis and the window.
+func fill(img image.Image, w, h int, place func(scaled *image.NRGBA, horizontal bool, length, window int) int) *image.NRGBA {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 || w <= 0 || h <= 0 {
+		return imaging.New(max(w, 0), max(h, 0), image.Transparent)
+	}
+
+	srcAspect := float64(b.Dx()) / float64(b.Dy())
+	dstAspect := float64(w) / float64(h)
+
+	var scaled *image.NRGBA
+	if srcAspect > dstAspect {
+		scaled = imaging.Resize(img, int(math.Round(float64(h)*srcAspect)), h, imaging.Lanczos)
+	} else {
+		scaled = imaging.Resize(img, w, int(math.Round(float64(w)/srcAspect)), imaging.Lanczos)
+	}
+
+	sw, sh := scaled.Bounds().Dx(), scaled.Bounds().Dy()
+	if sw == w && sh == h {
+		return scaled
+	}
+
+	if sw > w {
+		offset := place(scaled, true, sw, w)
+		return imaging.Crop(scaled, image.Rect(offset, 0, offset+w, h))
+	}
+	offset := place(scaled, false, sh, h)
+	return imaging.Crop(scaled, image.Rect(0, offset, w, offset+h))
+}
+
+// bestOffset slides a window of w x h along one axis of img and returns the
+// offset whose window has the highest sum of the profile.
+func bestOffset(img *image.NRGBA, horizontal bool, w, h int, profileOf func(*image.NRGBA, bool) []float64) int {
+	sw, sh := img.Bounds().Dx(), img.Bounds().Dy()
+	length, window := sh, h
+	if horizontal {
+		length, window = sw, w
+	}
+
+	scale := math.Min(1, float64(analysisSize)/float64(length))
+	small := imaging.Resize(img, max(int(float64(sw)*scale), 1), max(int(float64(sh)*scale), 1), imaging.Box)
+	profile := profileOf(small, horizontal)
+
+	win := min(max(int(math.Round(float64(window)*scale)), 1), len(profile))
+	var sum, best float64
+	bestStart := 0
+	for i := 0; i < win; i++ {
+		sum += profile[i]
+	}
+	best = sum
+	for start := 1; start+win <= len(profile); start++ {
+		sum += profile[start+win-1] - profile[start-1]
+		if sum > best {
+			best, bestStart = sum, start
+		}
+	}
+
+	offset := int(math.Round(float64(bestStart) / scale))
+	return min(max(offset, 0), length-window)
+}
+
+// energyProfile sums gradient magnitude per column (horizontal) or per row.
+func energyProfile(img *image.NRGBA, horizontal bool) []float64 {
+	return profile(img, horizontal, func(edge float64, _ []uint8) float64 { return edge })
+}
+
+// Saliency weights for attentionProfile, relative to gradient magnitude.
+const (
+	skinWeight       = 60
+	saturationWeight = 20
+)
+
+// attentionProfile is energyProfile with extra weight for skin tones, which
+// are usually faces and hands, and for saturated colour.
+func attentionProfile(img *image.NRGBA, horizontal bool) []float64 {
+	return profile(img, horizontal, func(edge float64, p []uint8) float64 {
+		r, g, b := float64(p[0]), float64(p[1]), float64(p[2])
+		hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+		alpha := float64(p[3]) / 255
+
+		var skin float64
+		if isSkin(p[0], p[1], p[2]) {
+			skin = 1
+		}
+		var saturation float64
+		if hi > 0 {
+			saturation = (hi - lo) / hi
+		}
+		return edge + (skinWeight*skin+saturationWeight*saturation)*alpha
+	})
+}
+
+// isSkin is the RGB skin-colour rule of Kovač et al. for daylight images.
+func isSkin(r, g, b uint8) bool {
+	hi, lo := max(r, g, b), min(r, g, b)
+	diff := int(r) - int(g)
+	return r > 95 && g > 40 && b > 20 && hi-lo > 15 && (diff > 15 || diff < -15) && r > g && r > b
+}
+
+// profile sums score per column (horizontal) or per row. score gets each
+// pixel's gradient magnitude and its RGBA bytes.
+func profile(img *image.NRGBA, horizontal bool, score func(edge float64, p []uint8) float64) []float64 {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	gray := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := img.PixOffset(x, y)
+			p := img.Pix[i : i+4]
+			gray[y*w+x] = (0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])) * float64(p[3]) / 255
+		}
+	}
+
+	size := h
+	if horizontal {
+		size = w
+	}
+	sums := make([]float64, size)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var gx, gy float64
+			if x+1 < w {
+				gx = gray[y*w+x+1] - gray[y*w+x]
+			}
+			if y+1 < h {
+				gy = gray[(y+1)*w+x] - gray[y*w+x]
+			}
+			i := img.PixOffset(x, y)
+			e := score(math.Abs(gx)+math.Abs(gy), img.Pix[i:i+4])
+			if horizontal {
+				sums[x] += e
+			} else {
+				sums[y] += e
+			}
+		}
+	}
+	return sums
+}
@@ -0,0 +1,131 @@
+package smartcrop
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// checkerAt returns a flat grey image with a high-contrast checkerboard block.
+func checkerAt(w, h int, block image.Rectangle) *image.NRGBA {
+	img := imaging.New(w, h, color.NRGBA{128, 128, 128, 255})
+	for y := block.Min.Y; y < block.Max.Y; y++ {
+		for x := block.Min.X; x < block.Max.X; x++ {
+			if (x/4+y/4)%2 == 0 {
+				img.SetNRGBA(x, y, color.NRGBA{0, 0, 0, 255})
+			} else {
+				img.SetNRGBA(x, y, color.NRGBA{255, 255, 255, 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestFill_ReturnsRequestedSize(t *testing.T) {
+	t.Parallel()
+
+	img := checkerAt(800, 600, image.Rect(0, 0, 100, 100))
+	for _, size := range [][2]int{{300, 300}, {100, 400}, {400, 100}, {800, 600}} {
+		out := Fill(img, size[0], size[1])
+		if out.Bounds().Dx() != size[0] || out.Bounds().Dy() != size[1] {
+			t.Fatalf("expected %dx%d, got %v", size[0], size[1], out.Bounds())
+		}
+	}
+}
+
+func TestFill_KeepsDetailedRegionHorizontally(t *testing.T) {
+	t.Parallel()
+
+	// Detail sits at the right edge of a landscape image cropped to a square.
+	img := checkerAt(900, 300, image.Rect(780, 0, 900, 300))
+	out := Fill(img, 300, 300)
+
+	right := out.NRGBAAt(290, 150)
+	if right.R == 128 {
+		t.Fatalf("expected checkerboard at the right edge, got flat grey")
+	}
+}
+
+func TestFill_KeepsDetailedRegionVertically(t *testing.T) {
+	t.Parallel()
+
+	// Detail sits at the top of a portrait image cropped to a square.
+	img := checkerAt(300, 900, image.Rect(0, 0, 300, 120))
+	out := Fill(img, 300, 300)
+
+	top := out.NRGBAAt(150, 10)
+	if top.R == 128 {
+		t.Fatalf("expected checkerboard at the top, got flat grey")
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	t.Parallel()
+
+	if s, err := ParseStrategy("Attention"); err != nil || s != Attention {
+		t.Fatalf("expected attention, got %q (%v)", s, err)
+	}
+	if _, err := ParseStrategy("faces"); err == nil {
+		t.Fatalf("expected error for unknown strategy")
+	}
+}
+
+func TestFillWith_CenterKeepsMiddle(t *testing.T) {
+	t.Parallel()
+
+	// Detail at the left edge must be cropped away by a centred window.
+	img := checkerAt(900, 300, image.Rect(0, 0, 120, 300))
+	out := FillWith(img, 300, 300, Center)
+	if c := out.NRGBAAt(10, 150); c.R != 128 {
+		t.Fatalf("expected flat grey at the left of a centred crop, got %v", c)
+	}
+}
+
+func TestFillWith_AttentionPrefersSkin(t *testing.T) {
+	t.Parallel()
+
+	// A flat skin-toned block on the left competes with grey texture on the
+	// right; entropy follows the texture, attention keeps the skin.
+	img := imaging.New(900, 300, color.NRGBA{40, 40, 40, 255})
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 900; x++ {
+			switch {
+			case x < 200:
+				img.SetNRGBA(x, y, color.NRGBA{224, 172, 140, 255})
+			case x > 700 && (x/8+y/8)%2 == 0:
+				img.SetNRGBA(x, y, color.NRGBA{70, 70, 70, 255})
+			}
+		}
+	}
+
+	if c := FillWith(img, 300, 300, Attention).NRGBAAt(10, 150); c.R < 200 {
+		t.Fatalf("expected attention to keep the skin tones, got %v", c)
+	}
+	if c := FillWith(img, 300, 300, Entropy).NRGBAAt(10, 150); c.R > 100 {
+		t.Fatalf("expected entropy to follow the texture, got %v", c)
+	}
+}
+
+func TestFillAt_CentresOnFocus(t *testing.T) {
+	t.Parallel()
+
+	img := checkerAt(900, 300, image.Rect(600, 0, 720, 300))
+	for _, focus := range []Focus{
+		{X: 0.7, Y: 0.5},
+		{X: 600, Y: 0, W: 120, H: 300, Pixels: true},
+	} {
+		out := FillAt(img, 300, 300, focus)
+		// The window is centred on x=630 or x=660, so the block is in the middle.
+		if c := out.NRGBAAt(150, 150); c.R == 128 {
+			t.Fatalf("expected the focus region in the middle for %+v", focus)
+		}
+	}
+
+	// A focus near the edge is clamped to the image.
+	out := FillAt(img, 300, 300, Focus{X: 1, Y: 0.5})
+	if c := out.NRGBAAt(295, 150); c.R != 128 {
+		t.Fatalf("expected the crop clamped to the right edge, got %v", c)
+	}
+}